@@ -0,0 +1,40 @@
+package dynamicticker
+
+import "time"
+
+// alignCheckInterval bounds how long an aligned ticker sleeps before it
+// compares its deadline with the wall clock again, so that a clock jump
+// delays or advances a tick by at most this long.
+const alignCheckInterval = time.Second
+
+type alignment struct {
+	offset time.Duration
+	loc    *time.Location
+}
+
+// WithAlignment makes ticks land on wall-clock multiples of the period in loc,
+// shifted by offset, e.g. a 15m period with a 1m offset ticks at :01, :16, :31
+// and :46. A nil loc means time.Local.
+func WithAlignment(offset time.Duration, loc *time.Location) Option {
+	return func(o *Options) {
+		if loc == nil {
+			loc = time.Local
+		}
+		o.alignment = &alignment{offset: offset, loc: loc}
+	}
+}
+
+// next returns the first boundary strictly after now. The result carries no
+// monotonic reading, so waiting for it follows the wall clock.
+func (a *alignment) next(now time.Time, period time.Duration) time.Time {
+	_, zoneOffset := now.In(a.loc).Zone()
+	shift := time.Duration(zoneOffset)*time.Second - a.offset
+
+	wall := now.UnixNano() + int64(shift)
+	boundary := (wall/int64(period) + 1) * int64(period)
+	if wall < 0 && wall%int64(period) != 0 {
+		boundary -= int64(period)
+	}
+
+	return time.Unix(0, boundary-int64(shift))
+}
@@ -0,0 +1,85 @@
+package dynamicticker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlignment_Next(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	kathmandu := time.FixedZone("NPT", 5*3600+45*60)
+
+	tables := []struct {
+		now    time.Time
+		period time.Duration
+		offset time.Duration
+		loc    *time.Location
+		next   time.Time
+	}{
+		{
+			now:    time.Date(2024, 5, 1, 10, 7, 30, 0, time.UTC),
+			period: 15 * time.Minute,
+			loc:    time.UTC,
+			next:   time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC),
+		},
+		{
+			now:    time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC),
+			period: 15 * time.Minute,
+			loc:    time.UTC,
+			next:   time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			now:    time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC),
+			period: 15 * time.Minute,
+			offset: time.Minute,
+			loc:    time.UTC,
+			next:   time.Date(2024, 5, 1, 10, 16, 0, 0, time.UTC),
+		},
+		{
+			now:    time.Date(2024, 5, 1, 10, 7, 0, 0, shanghai),
+			period: 24 * time.Hour,
+			loc:    shanghai,
+			next:   time.Date(2024, 5, 2, 0, 0, 0, 0, shanghai),
+		},
+		{
+			now:    time.Date(2024, 5, 1, 10, 7, 0, 0, kathmandu),
+			period: time.Hour,
+			loc:    kathmandu,
+			next:   time.Date(2024, 5, 1, 11, 0, 0, 0, kathmandu),
+		},
+	}
+
+	for _, table := range tables {
+		a := &alignment{offset: table.offset, loc: table.loc}
+		assert.True(t, table.next.Equal(a.next(table.now, table.period)), "now %s, expected %s, got %s", table.now, table.next, a.next(table.now, table.period))
+	}
+}
+
+func TestDynamicTicker_Aligned(t *testing.T) {
+	var mu sync.Mutex
+	var ticks []time.Time
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 50*time.Millisecond, func() {
+		mu.Lock()
+		ticks = append(ticks, time.Now())
+		mu.Unlock()
+	}, WithAlignment(0, time.UTC))
+
+	go dt.Run()
+	time.Sleep(230 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.True(t, len(ticks) >= 3, "expected at least 3 ticks, got %d", len(ticks))
+	for _, tick := range ticks {
+		drift := time.Duration(tick.UnixNano() % int64(50*time.Millisecond))
+		assert.True(t, drift < 15*time.Millisecond, "tick %s is %s past the boundary", tick, drift)
+	}
+}
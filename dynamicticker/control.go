@@ -0,0 +1,127 @@
+package dynamicticker
+
+import "time"
+
+type State int
+
+const (
+	// StateIdle means Run has not been called yet.
+	StateIdle State = iota
+	StateActive
+	StatePaused
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateActive:
+		return "active"
+	case StatePaused:
+		return "paused"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// WithRunOnStart runs the task as soon as Run starts instead of waiting for
+// the first period to elapse.
+func WithRunOnStart() Option { return func(o *Options) { o.runOnStart = true } }
+
+// TriggerNow runs the task once without waiting for the next tick, subject to
+// the overlap policy. Triggers before Run are delivered when it starts and
+// several pending triggers collapse into one.
+func (dt *DynamicTicker) TriggerNow() {
+	select {
+	case dt.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Pause stops the ticks until Resume. TriggerNow still runs the task.
+func (dt *DynamicTicker) Pause() {
+	dt.mu.Lock()
+	dt.paused = true
+	dt.mu.Unlock()
+
+	dt.notify()
+}
+
+// Resume restarts the ticks after Pause or after the period was set to zero
+// or a negative value, in which case the last positive period is restored.
+func (dt *DynamicTicker) Resume() {
+	dt.mu.Lock()
+	dt.paused = false
+	if dt.cur <= 0 {
+		dt.cur = dt.lastPeriod
+	}
+	dt.mu.Unlock()
+
+	dt.notify()
+}
+
+// Period returns the current period, which is not positive when the ticker
+// was paused through SetPeriod.
+func (dt *DynamicTicker) Period() time.Duration {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	return dt.cur
+}
+
+func (dt *DynamicTicker) State() State {
+	if dt.ctx.Err() != nil {
+		return StateStopped
+	}
+
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	switch {
+	case !dt.started:
+		return StateIdle
+	case dt.paused || dt.cur <= 0:
+		return StatePaused
+	default:
+		return StateActive
+	}
+}
+
+// setCur must be called with dt.mu held.
+func (dt *DynamicTicker) setCur(d time.Duration) {
+	dt.cur = d
+	if d > 0 {
+		dt.lastPeriod = d
+	}
+}
+
+// effectivePeriod must be called with dt.mu held.
+func (dt *DynamicTicker) effectivePeriod() time.Duration {
+	if dt.paused {
+		return 0
+	}
+
+	return dt.cur
+}
+
+func (dt *DynamicTicker) notify() {
+	select {
+	case dt.wake <- struct{}{}:
+	default:
+	}
+}
+
+// reset sets the period to d and resumes the ticker like SetPeriod and
+// Resume, but also counts the next tick from now when d is unchanged.
+func (dt *DynamicTicker) reset(d time.Duration) {
+	dt.mu.Lock()
+	dt.paused = false
+	dt.setCur(d)
+	dt.restart = true
+	dt.mu.Unlock()
+
+	dt.notify()
+}
@@ -0,0 +1,115 @@
+package dynamicticker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicTicker_TriggerNow(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, time.Hour, func() {
+		atomic.AddInt32(&count, 1)
+	})
+
+	dt.TriggerNow()
+	dt.TriggerNow()
+
+	go dt.Run()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count), "pending triggers should collapse into one run")
+
+	dt.TriggerNow()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestDynamicTicker_PauseResume(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 20*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+	})
+	assert.Equal(t, StateIdle, dt.State())
+
+	go dt.Run()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, StateActive, dt.State())
+
+	dt.Pause()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, StatePaused, dt.State())
+	assert.Equal(t, 20*time.Millisecond, dt.Period())
+
+	paused := atomic.LoadInt32(&count)
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, paused, atomic.LoadInt32(&count))
+
+	dt.Resume()
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&count) > paused)
+
+	dt.Stop()
+	assert.Equal(t, StateStopped, dt.State())
+}
+
+func TestDynamicTicker_ResumeRestoresPeriod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 20*time.Millisecond, func() {})
+
+	go dt.Run()
+
+	dt.SetPeriod(0)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, StatePaused, dt.State())
+
+	dt.Resume()
+	assert.Equal(t, 20*time.Millisecond, dt.Period())
+	assert.Equal(t, StateActive, dt.State())
+}
+
+func TestDynamicTicker_RunOnStart(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, time.Hour, func() {
+		atomic.AddInt32(&count, 1)
+	}, WithRunOnStart())
+
+	go dt.Run()
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&count))
+}
+
+func TestDynamicTicker_ControlAfterStop(t *testing.T) {
+	dt := NewDynamicTicker(context.Background(), 20*time.Millisecond, func() {})
+	dt.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		dt.TriggerNow()
+		dt.TriggerNow()
+		dt.Pause()
+		dt.Resume()
+		dt.Pause()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("control methods blocked after Stop")
+	}
+}
@@ -0,0 +1,112 @@
+package dynamicticker
+
+import "time"
+
+// Result is the hint a task returns about the work it found.
+type Result int
+
+const (
+	// ResultNone leaves the period to the controller's discretion, most
+	// controllers keep it unchanged.
+	ResultNone Result = iota
+	// ResultIdle reports that there was nothing to do.
+	ResultIdle
+	// ResultBusy reports that work was found.
+	ResultBusy
+	// ResultDone reports that the work is complete and stops the ticker.
+	ResultDone
+)
+
+// Observation describes one finished run of the task.
+type Observation struct {
+	Period  time.Duration
+	Result  Result
+	Elapsed time.Duration
+}
+
+// Controller computes the next period after every run. A non-positive
+// return value keeps the current period.
+type Controller interface {
+	Next(o Observation) time.Duration
+}
+
+type ControllerFunc func(o Observation) time.Duration
+
+func (f ControllerFunc) Next(o Observation) time.Duration { return f(o) }
+
+// ExponentialBackoff multiplies the period by Factor (2 by default) on every
+// idle run and drops back to Min as soon as work is found.
+type ExponentialBackoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+func (c ExponentialBackoff) Next(o Observation) time.Duration {
+	switch o.Result {
+	case ResultIdle:
+		factor := c.Factor
+		if factor <= 1 {
+			factor = 2
+		}
+
+		return clamp(time.Duration(float64(o.Period)*factor), c.Min, c.Max)
+	case ResultBusy:
+		return clamp(c.Min, c.Min, c.Max)
+	default:
+		return o.Period
+	}
+}
+
+// AIMD grows the period by Step on every idle run and multiplies it by
+// Decrease (0.5 by default) when work is found, so the poller slows down
+// gradually and speeds up quickly.
+type AIMD struct {
+	Min      time.Duration
+	Max      time.Duration
+	Step     time.Duration
+	Decrease float64
+}
+
+func (c AIMD) Next(o Observation) time.Duration {
+	switch o.Result {
+	case ResultIdle:
+		return clamp(o.Period+c.Step, c.Min, c.Max)
+	case ResultBusy:
+		decrease := c.Decrease
+		if decrease <= 0 || decrease >= 1 {
+			decrease = 0.5
+		}
+
+		return clamp(time.Duration(float64(o.Period)*decrease), c.Min, c.Max)
+	default:
+		return o.Period
+	}
+}
+
+// TargetUtilization keeps the share of the period spent running the task
+// close to Target, e.g. 0.5 picks a period twice as long as the last run.
+type TargetUtilization struct {
+	Min    time.Duration
+	Max    time.Duration
+	Target float64
+}
+
+func (c TargetUtilization) Next(o Observation) time.Duration {
+	if c.Target <= 0 || c.Target > 1 {
+		return clamp(o.Period, c.Min, c.Max)
+	}
+
+	return clamp(time.Duration(float64(o.Elapsed)/c.Target), c.Min, c.Max)
+}
+
+func clamp(d, min, max time.Duration) time.Duration {
+	if min > 0 && d < min {
+		d = min
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+
+	return d
+}
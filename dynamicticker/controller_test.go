@@ -0,0 +1,56 @@
+package dynamicticker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoff_Next(t *testing.T) {
+	c := ExponentialBackoff{Min: 10 * time.Millisecond, Max: 100 * time.Millisecond}
+
+	assert.Equal(t, 20*time.Millisecond, c.Next(Observation{Period: 10 * time.Millisecond, Result: ResultIdle}))
+	assert.Equal(t, 100*time.Millisecond, c.Next(Observation{Period: 80 * time.Millisecond, Result: ResultIdle}))
+	assert.Equal(t, 10*time.Millisecond, c.Next(Observation{Period: 80 * time.Millisecond, Result: ResultBusy}))
+	assert.Equal(t, 80*time.Millisecond, c.Next(Observation{Period: 80 * time.Millisecond, Result: ResultNone}))
+}
+
+func TestAIMD_Next(t *testing.T) {
+	c := AIMD{Min: 10 * time.Millisecond, Max: 100 * time.Millisecond, Step: 5 * time.Millisecond}
+
+	assert.Equal(t, 45*time.Millisecond, c.Next(Observation{Period: 40 * time.Millisecond, Result: ResultIdle}))
+	assert.Equal(t, 20*time.Millisecond, c.Next(Observation{Period: 40 * time.Millisecond, Result: ResultBusy}))
+	assert.Equal(t, 10*time.Millisecond, c.Next(Observation{Period: 15 * time.Millisecond, Result: ResultBusy}))
+}
+
+func TestTargetUtilization_Next(t *testing.T) {
+	c := TargetUtilization{Min: 10 * time.Millisecond, Max: time.Second, Target: 0.25}
+
+	assert.Equal(t, 200*time.Millisecond, c.Next(Observation{Period: time.Second, Elapsed: 50 * time.Millisecond}))
+	assert.Equal(t, 10*time.Millisecond, c.Next(Observation{Period: time.Second, Elapsed: time.Millisecond}))
+}
+
+func TestDynamicTicker_AdaptiveBackoff(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewAdaptiveTicker(ctx, 20*time.Millisecond, func() Result {
+		atomic.AddInt32(&count, 1)
+		return ResultIdle
+	}, WithController(ExponentialBackoff{Min: 20 * time.Millisecond, Max: 160 * time.Millisecond}))
+
+	go dt.Run()
+	time.Sleep(400 * time.Millisecond)
+
+	// 20 + 40 + 80 + 160 + 160 ms
+	n := atomic.LoadInt32(&count)
+	assert.True(t, n >= 3 && n <= 6, "expected the ticker to back off, got %d ticks", n)
+
+	dt.mu.Lock()
+	assert.Equal(t, 160*time.Millisecond, dt.cur)
+	dt.mu.Unlock()
+}
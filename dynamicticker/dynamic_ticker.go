@@ -2,94 +2,365 @@ package dynamicticker
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/pqiaohaoq/gotools/log"
+	"go.uber.org/zap"
 )
 
+var defaultOptions = &Options{
+	logger: zap.NewNop().Sugar(),
+}
+
 type DynamicTicker struct {
+	// first for 64-bit atomic alignment
+	skipped uint64
+	gated   uint64
+
 	ctx    context.Context
 	cancel context.CancelFunc
-	period chan time.Duration
-	task   func()
+	task   func(ctx context.Context) (Result, error)
 	mu     sync.Mutex
 	cur    time.Duration
+
+	lastPeriod time.Duration
+	paused     bool
+	restart    bool
+	started    bool
+	// stoppedEarly means Stop was called before Run.
+	stoppedEarly bool
+	stats        stats
+	health       Health
+
+	trigger chan struct{}
+	wake    chan struct{}
+
+	inflight sync.WaitGroup
+	done     chan struct{}
+
+	controller  Controller
+	overlap     OverlapPolicy
+	maxInFlight int
+
+	backoffFactor float64
+	backoffMax    time.Duration
+
+	alignment *alignment
+	jitter    Jitter
+	rand      *rand.Rand
+
+	runOnStart bool
+	maxRuns    int
+	deadline   time.Time
+
+	onTick         func(info RunInfo)
+	onPeriodChange func(old, new time.Duration)
+	onStop         func(reason StopReason)
+
+	periodSource PeriodSource
+	minPeriod    time.Duration
+	maxPeriod    time.Duration
+
+	supervisor *Supervisor
+	gate       Gate
+
+	logger log.Logger
+}
+
+type Option func(*Options)
+
+type Options struct {
+	controller    Controller
+	overlap       OverlapPolicy
+	maxConcurrent int
+
+	backoffFactor float64
+	backoffMax    time.Duration
+
+	alignment *alignment
+	jitter    Jitter
+	rand      *rand.Rand
+
+	runOnStart bool
+	maxRuns    int
+	deadline   time.Time
+
+	onTick         func(info RunInfo)
+	onPeriodChange func(old, new time.Duration)
+	onStop         func(reason StopReason)
+
+	periodSource PeriodSource
+	minPeriod    time.Duration
+	maxPeriod    time.Duration
+
+	supervisor *Supervisor
+	gate       Gate
+
+	logger log.Logger
 }
 
-func NewDynamicTicker(ctx context.Context, d time.Duration, task func()) *DynamicTicker {
+// WithController lets c adjust the period after every run of the task.
+func WithController(c Controller) Option { return func(o *Options) { o.controller = c } }
+
+// WithErrorBackoff multiplies the period by factor for every consecutive
+// failed run, up to max. The first successful run restores the period.
+func WithErrorBackoff(factor float64, max time.Duration) Option {
+	return func(o *Options) {
+		o.backoffFactor = factor
+		o.backoffMax = max
+	}
+}
+
+func WithLogger(l log.Logger) Option { return func(o *Options) { o.logger = l } }
+
+func applyOpts(opts []Option) Options {
+	o := *defaultOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func NewDynamicTicker(ctx context.Context, d time.Duration, task func(), opts ...Option) *DynamicTicker {
+	return newDynamicTicker(ctx, d, func(context.Context) (Result, error) {
+		task()
+
+		return ResultNone, nil
+	}, opts)
+}
+
+// NewAdaptiveTicker creates a ticker whose task reports a Result, which the
+// controller set by WithController turns into the next period.
+func NewAdaptiveTicker(ctx context.Context, d time.Duration, task func() Result, opts ...Option) *DynamicTicker {
+	return newDynamicTicker(ctx, d, func(context.Context) (Result, error) {
+		return task(), nil
+	}, opts)
+}
+
+// NewDynamicTickerCtx creates a ticker whose task observes cancellation through
+// ctx, which is cancelled by Stop, and reports failures through its error.
+func NewDynamicTickerCtx(ctx context.Context, d time.Duration, task func(ctx context.Context) error, opts ...Option) *DynamicTicker {
+	return newDynamicTicker(ctx, d, func(ctx context.Context) (Result, error) {
+		err := task(ctx)
+		if errors.Is(err, ErrDone) {
+			return ResultDone, nil
+		}
+
+		return ResultNone, err
+	}, opts)
+}
+
+func newDynamicTicker(ctx context.Context, d time.Duration, task func(ctx context.Context) (Result, error), opts []Option) *DynamicTicker {
+	o := applyOpts(opts)
 	childCtx, cancel := context.WithCancel(ctx)
 
-	return &DynamicTicker{
-		ctx:    childCtx,
-		cancel: cancel,
-		period: make(chan time.Duration, 1),
-		cur:    d,
-		task:   task,
+	dt := &DynamicTicker{
+		ctx:         childCtx,
+		cancel:      cancel,
+		task:        task,
+		controller:  o.controller,
+		overlap:     o.overlap,
+		maxInFlight: o.maxInFlight(),
+
+		backoffFactor: o.backoffFactor,
+		backoffMax:    o.backoffMax,
+
+		alignment: o.alignment,
+		jitter:    o.jitter,
+		rand:      o.rand,
+
+		runOnStart: o.runOnStart,
+		maxRuns:    o.maxRuns,
+		deadline:   o.deadline,
+
+		onTick:         o.onTick,
+		onPeriodChange: o.onPeriodChange,
+		onStop:         o.onStop,
+
+		periodSource: o.periodSource,
+		minPeriod:    o.minPeriod,
+		maxPeriod:    o.maxPeriod,
+
+		supervisor: o.supervisor,
+		gate:       o.gate,
+
+		trigger: make(chan struct{}, 1),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+
+		logger: o.logger,
 	}
+	if dt.rand == nil {
+		dt.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	dt.setCur(d)
+
+	return dt
 }
 
-func (dt *DynamicTicker) Run() {
-	var ticker *time.Ticker
-	defer func() {
-		if ticker != nil {
-			ticker.Stop()
+// Run drives the ticker until Stop is called, the context is cancelled or a
+// run limit is reached, and reports which one happened. It returns without
+// waiting for runs in flight, use Wait for that. Only the first call runs the
+// ticker, later calls return ReasonAlreadyRunning immediately.
+func (dt *DynamicTicker) Run() StopReason {
+	dt.mu.Lock()
+	if dt.started {
+		stoppedEarly := dt.stoppedEarly
+		dt.mu.Unlock()
+
+		if stoppedEarly {
+			return ReasonCancelled
 		}
-	}()
 
-	if dt.cur > 0 {
-		ticker = time.NewTicker(dt.cur)
+		dt.logger.Warnf("[dynamicticker] Run is called more than once")
+
+		return ReasonAlreadyRunning
 	}
+	dt.started = true
+	dt.mu.Unlock()
 
-	for {
-		select {
-		case <-dt.ctx.Done():
-			return
+	defer func() {
+		dt.cancel()
 
-		case d := <-dt.period:
-			if d <= 0 {
-				if ticker != nil {
-					ticker.Stop()
-					ticker = nil
-				}
+		go func() {
+			dt.inflight.Wait()
+			close(dt.done)
+		}()
+	}()
 
-				dt.mu.Lock()
-				dt.cur = d
-				dt.mu.Unlock()
+	if dt.periodSource != nil {
+		go dt.periodSource.Watch(dt.ctx, dt.applySourcePeriod)
+	}
+	if n, ok := dt.gate.(OpenNotifier); ok {
+		go n.NotifyOpen(dt.ctx, dt.TriggerNow)
+	}
 
-				continue
-			}
+	var reason StopReason
+	if dt.supervisor != nil {
+		reason = dt.supervise()
+	} else {
+		reason = newRunner(dt).run()
+	}
+	dt.logger.Debugf("[dynamicticker] stop the ticker: %s", reason)
+
+	if dt.onStop != nil {
+		dt.onStop(reason)
+	}
 
-			dt.mu.Lock()
-			dt.cur = d
-			dt.mu.Unlock()
+	return reason
+}
 
-			if ticker == nil {
-				ticker = time.NewTicker(d)
-			} else {
-				ticker.Reset(d)
-			}
+// runTask runs the task once, turning a panic into an error.
+func (dt *DynamicTicker) runTask() (r runResult) {
+	begin := time.Now()
+	dt.recordStart(begin)
 
-		case <-func() <-chan time.Time {
-			if ticker == nil {
-				return nil
+	defer func() {
+		if p := recover(); p != nil {
+			dt.logger.Errorf("[dynamicticker] task panicked: %v\n%s", p, debug.Stack())
+
+			r = runResult{
+				err:     fmt.Errorf("dynamicticker: task panicked: %v", p),
+				panic:   p,
+				start:   begin,
+				elapsed: time.Since(begin),
 			}
-			return ticker.C
-		}():
-			dt.task()
 		}
+	}()
+
+	result, err := dt.task(dt.ctx)
+
+	return runResult{result: result, err: err, start: begin, elapsed: time.Since(begin)}
+}
+
+// backoff stretches the period d after consecutive failures.
+func (dt *DynamicTicker) backoff(d time.Duration, failures int) time.Duration {
+	if failures == 0 || dt.backoffFactor <= 1 {
+		return d
+	}
+
+	backoff := float64(d) * math.Pow(dt.backoffFactor, float64(failures))
+	if dt.backoffMax > 0 && backoff > float64(dt.backoffMax) {
+		return dt.backoffMax
+	}
+	// Converting a float beyond the range of int64 gives a negative period,
+	// which would pause the ticker.
+	if backoff >= math.MaxInt64 {
+		return math.MaxInt64
 	}
+
+	return time.Duration(backoff)
 }
 
-func (dt *DynamicTicker) SetPeriod(d time.Duration) {
+// Skipped returns the number of ticks dropped because the task was still
+// running.
+func (dt *DynamicTicker) Skipped() uint64 {
+	return atomic.LoadUint64(&dt.skipped)
+}
+
+// adapt asks the controller for the next period and returns the period the
+// ticker should run at, zero while paused.
+func (dt *DynamicTicker) adapt(result Result, elapsed time.Duration) time.Duration {
 	dt.mu.Lock()
 	defer dt.mu.Unlock()
 
+	if dt.effectivePeriod() <= 0 || dt.controller == nil {
+		return dt.effectivePeriod()
+	}
+
+	if d := dt.controller.Next(Observation{Period: dt.cur, Result: result, Elapsed: elapsed}); d > 0 {
+		dt.setCur(d)
+	}
+
+	return dt.cur
+}
+
+// SetPeriod changes the period, a zero or negative value pauses the ticker.
+// It never blocks, and when called several times before the loop picks the
+// change up, the latest value wins.
+func (dt *DynamicTicker) SetPeriod(d time.Duration) {
+	dt.mu.Lock()
 	if d == dt.cur {
+		dt.mu.Unlock()
 		return
 	}
+	dt.setCur(d)
+	dt.mu.Unlock()
 
-	dt.period <- d
+	dt.notify()
 }
 
 func (dt *DynamicTicker) Stop() {
 	dt.cancel()
+
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	// Nothing will ever close done if Run was not called, so keep it from
+	// starting and close done here.
+	if !dt.started {
+		dt.started = true
+		dt.stoppedEarly = true
+		close(dt.done)
+	}
+}
+
+// Done returns a channel that is closed once Run has returned and the runs in
+// flight have finished.
+func (dt *DynamicTicker) Done() <-chan struct{} {
+	return dt.done
+}
+
+// Wait blocks until Done is closed.
+func (dt *DynamicTicker) Wait() {
+	<-dt.done
 }
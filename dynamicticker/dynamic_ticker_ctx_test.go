@@ -0,0 +1,86 @@
+package dynamicticker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicTicker_CtxCancelledOnStop(t *testing.T) {
+	cancelled := make(chan struct{})
+
+	dt := NewDynamicTickerCtx(context.Background(), 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+
+	go dt.Run()
+	time.Sleep(30 * time.Millisecond)
+	dt.Stop()
+
+	select {
+	case <-cancelled:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("task context was not cancelled by Stop")
+	}
+}
+
+func TestDynamicTicker_ErrorBackoff(t *testing.T) {
+	var count int32
+	var failing int32 = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTickerCtx(ctx, 10*time.Millisecond, func(context.Context) error {
+		atomic.AddInt32(&count, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}, WithErrorBackoff(2, 80*time.Millisecond))
+
+	go dt.Run()
+	time.Sleep(300 * time.Millisecond)
+
+	// 10 + 20 + 40 + 80 + 80 + 80 ms
+	failed := atomic.LoadInt32(&count)
+	assert.True(t, failed >= 4 && failed <= 7, "expected the ticker to back off on errors, got %d runs", failed)
+
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(200 * time.Millisecond)
+
+	assert.True(t, atomic.LoadInt32(&count)-failed >= 10, "expected the period to reset after a success")
+}
+
+func TestDynamicTicker_ErrorBackoffOverflow(t *testing.T) {
+	dt := NewDynamicTicker(context.Background(), 1000*time.Second, func() {}, WithErrorBackoff(1000, 0))
+
+	assert.Equal(t, 1000*time.Second, dt.backoff(1000*time.Second, 0))
+	assert.Equal(t, 1000000*time.Second, dt.backoff(1000*time.Second, 1))
+	// Beyond the range of a time.Duration the period saturates instead of
+	// wrapping to a negative value that pauses the ticker.
+	assert.Equal(t, time.Duration(math.MaxInt64), dt.backoff(1000*time.Second, 3))
+	assert.Equal(t, time.Duration(math.MaxInt64), dt.backoff(1000*time.Second, 50))
+}
+
+func TestDynamicTicker_RecoverPanic(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 20*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+		panic("boom")
+	})
+
+	go dt.Run()
+	time.Sleep(110 * time.Millisecond)
+
+	assert.True(t, atomic.LoadInt32(&count) >= 3, "expected the ticker to keep running after a panic")
+}
@@ -0,0 +1,9 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package dynamicticker
+
+import "os"
+
+func tryLockFile(*os.File) (bool, error) { return false, ErrFileLockUnsupported }
+
+func unlockFile(*os.File) error { return ErrFileLockUnsupported }
@@ -0,0 +1,222 @@
+package dynamicticker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pqiaohaoq/gotools/log"
+	"go.uber.org/zap"
+)
+
+var ErrFileLockUnsupported = errors.New("dynamicticker: file locks are not supported on this platform")
+
+// Gate decides on every tick whether the task may run, e.g. only on the
+// leader replica. Allow is called from the Run loop and must not block.
+type Gate interface {
+	Allow(ctx context.Context) bool
+}
+
+// OpenNotifier is implemented by gates that can tell when they open. The
+// ticker then runs the task right away instead of waiting for the next tick.
+type OpenNotifier interface {
+	// NotifyOpen calls open every time the gate opens until ctx is done.
+	NotifyOpen(ctx context.Context, open func())
+}
+
+// WithGate skips the ticks for which g does not allow to run the task, see
+// Stats.Gated. TriggerNow is gated too.
+func WithGate(g Gate) Option { return func(o *Options) { o.gate = g } }
+
+func (dt *DynamicTicker) allow() bool {
+	if dt.gate == nil || dt.gate.Allow(dt.ctx) {
+		return true
+	}
+
+	dt.logger.Debugf("[dynamicticker] the gate is closed, skip the tick")
+
+	return false
+}
+
+// GateSkipped returns the number of ticks dropped because the gate was closed.
+func (dt *DynamicTicker) GateSkipped() uint64 {
+	return atomic.LoadUint64(&dt.gated)
+}
+
+// GateFunc turns a function into a Gate.
+type GateFunc func(ctx context.Context) bool
+
+func (f GateFunc) Allow(ctx context.Context) bool { return f(ctx) }
+
+// FlagGate is an in-process Gate, e.g. set by a leader election callback.
+type FlagGate struct {
+	mu   sync.Mutex
+	open bool
+	subs map[chan struct{}]struct{}
+}
+
+func NewFlagGate(open bool) *FlagGate {
+	return &FlagGate{open: open, subs: make(map[chan struct{}]struct{})}
+}
+
+func (g *FlagGate) Allow(context.Context) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.open
+}
+
+func (g *FlagGate) Set(open bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if open == g.open {
+		return
+	}
+
+	g.open = open
+	if !open {
+		return
+	}
+
+	for ch := range g.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (g *FlagGate) NotifyOpen(ctx context.Context, open func()) {
+	ch := make(chan struct{}, 1)
+
+	g.mu.Lock()
+	g.subs[ch] = struct{}{}
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.subs, ch)
+		g.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			open()
+		}
+	}
+}
+
+// FileLockGate opens for the process holding an exclusive flock on Path, so
+// that a single process per host runs the task. A process that does not hold
+// the lock tries to take it on every tick and every Interval, one second by
+// default. The lock is kept until Release or the exit of the process.
+type FileLockGate struct {
+	Path     string
+	Interval time.Duration
+	Logger   log.Logger
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (g *FileLockGate) Allow(context.Context) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.acquire()
+}
+
+// acquire must be called with g.mu held.
+func (g *FileLockGate) acquire() bool {
+	if g.file != nil {
+		return true
+	}
+
+	f, err := os.OpenFile(g.Path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		g.logger().Warnf("[dynamicticker] open the lock file %s: %v", g.Path, err)
+		return false
+	}
+
+	locked, err := tryLockFile(f)
+	if err != nil {
+		g.logger().Warnf("[dynamicticker] lock the file %s: %v", g.Path, err)
+	}
+	if !locked {
+		_ = f.Close()
+		return false
+	}
+
+	g.file = f
+	g.logger().Infof("[dynamicticker] acquire the lock file %s", g.Path)
+
+	return true
+}
+
+// Held reports whether this gate holds the lock.
+func (g *FileLockGate) Held() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.file != nil
+}
+
+// Release gives the lock up so that another process can take it.
+func (g *FileLockGate) Release() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.file == nil {
+		return nil
+	}
+
+	f := g.file
+	g.file = nil
+
+	err := unlockFile(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (g *FileLockGate) NotifyOpen(ctx context.Context, open func()) {
+	interval := g.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.mu.Lock()
+			opened := g.file == nil && g.acquire()
+			g.mu.Unlock()
+
+			if opened {
+				open()
+			}
+		}
+	}
+}
+
+func (g *FileLockGate) logger() log.Logger {
+	if g.Logger == nil {
+		return zap.NewNop().Sugar()
+	}
+
+	return g.Logger
+}
@@ -0,0 +1,76 @@
+package dynamicticker
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicTicker_FlagGate(t *testing.T) {
+	var count int32
+
+	gate := NewFlagGate(false)
+	dt := NewDynamicTicker(context.Background(), 5*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+	}, WithGate(gate))
+	go dt.Run()
+	defer dt.Stop()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&count))
+	assert.True(t, dt.GateSkipped() >= 3)
+	assert.Equal(t, dt.GateSkipped(), dt.Stats().Gated)
+
+	// Opening the gate runs the task right away, long before the next tick.
+	dt.SetPeriod(time.Hour)
+	time.Sleep(5 * time.Millisecond)
+	gate.Set(true)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&count) == 1 }, time.Second, time.Millisecond)
+}
+
+func TestDynamicTicker_GateFunc(t *testing.T) {
+	var allowed int32
+
+	dt := NewDynamicTicker(context.Background(), 2*time.Millisecond, func() {}, WithMaxRuns(3),
+		WithGate(GateFunc(func(context.Context) bool {
+			return atomic.AddInt32(&allowed, 1)%2 == 0
+		})))
+
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+	assert.True(t, dt.GateSkipped() >= 2)
+}
+
+func TestFileLockGate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+
+	leader := &FileLockGate{Path: path}
+	standby := &FileLockGate{Path: path, Interval: 5 * time.Millisecond}
+
+	assert.True(t, leader.Allow(context.Background()))
+	assert.True(t, leader.Allow(context.Background()))
+	assert.False(t, standby.Allow(context.Background()))
+	assert.True(t, leader.Held())
+	assert.False(t, standby.Held())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opened := make(chan struct{}, 1)
+	go standby.NotifyOpen(ctx, func() { opened <- struct{}{} })
+
+	assert.NoError(t, leader.Release())
+	assert.NoError(t, leader.Release())
+
+	select {
+	case <-opened:
+	case <-time.After(time.Second):
+		t.Fatal("the standby did not take the lock over")
+	}
+	assert.True(t, standby.Held())
+	assert.False(t, leader.Allow(context.Background()))
+	assert.NoError(t, standby.Release())
+}
@@ -0,0 +1,342 @@
+package dynamicticker
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pqiaohaoq/gotools/log"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTaskNameIsEmpty    = errors.New("task name is empty")
+	ErrTaskDuplicatedName = errors.New("duplicated task name")
+	ErrTaskNotFound       = errors.New("task not found")
+)
+
+var defaultGroupOptions = &GroupOptions{
+	workers: runtime.NumCPU(),
+	logger:  zap.NewNop().Sugar(),
+}
+
+type GroupOption func(*GroupOptions)
+
+type GroupOptions struct {
+	workers int
+	logger  log.Logger
+}
+
+// WithWorkers bounds the number of tasks of a group running at the same time.
+func WithWorkers(n int) GroupOption            { return func(o *GroupOptions) { o.workers = n } }
+func WithGroupLogger(l log.Logger) GroupOption { return func(o *GroupOptions) { o.logger = l } }
+
+func applyGroupOpts(opts []GroupOption) GroupOptions {
+	o := *defaultGroupOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.workers <= 0 {
+		o.workers = 1
+	}
+
+	return o
+}
+
+// Group multiplexes many named periodic tasks on a single scheduling
+// goroutine and runs them on a bounded pool of workers. Due tasks wait in FIFO
+// order for a free worker. A task never overlaps with itself, ticks that fire
+// while it is still waiting or running are skipped.
+type Group struct {
+	skipped uint64 // first for 64-bit atomic alignment
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	tasks map[string]*groupTask
+	queue groupQueue
+	ready []*groupTask
+	cond  *sync.Cond
+
+	wake chan struct{}
+
+	workers int
+	logger  log.Logger
+}
+
+type groupTask struct {
+	name   string
+	period time.Duration
+	next   time.Time
+	// index is the position in the queue, -1 while paused or removed.
+	index int
+	// busy is set while the task waits for a worker or runs.
+	busy bool
+
+	task func(ctx context.Context) error
+}
+
+type groupQueue []*groupTask
+
+func (q groupQueue) Len() int           { return len(q) }
+func (q groupQueue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+func (q groupQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *groupQueue) Push(x any) {
+	t := x.(*groupTask)
+	t.index = len(*q)
+	*q = append(*q, t)
+}
+
+func (q *groupQueue) Pop() any {
+	old := *q
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*q = old[:n-1]
+
+	return t
+}
+
+func NewGroup(ctx context.Context, opts ...GroupOption) *Group {
+	o := applyGroupOpts(opts)
+	childCtx, cancel := context.WithCancel(ctx)
+
+	g := &Group{
+		ctx:    childCtx,
+		cancel: cancel,
+
+		tasks: make(map[string]*groupTask),
+
+		wake: make(chan struct{}, 1),
+
+		workers: o.workers,
+		logger:  o.logger,
+	}
+	g.cond = sync.NewCond(&g.mu)
+
+	return g
+}
+
+// Add registers a task under name. A zero or negative period adds it paused.
+func (g *Group) Add(name string, d time.Duration, task func(ctx context.Context) error) error {
+	if name == "" {
+		return ErrTaskNameIsEmpty
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.tasks[name]; ok {
+		return ErrTaskDuplicatedName
+	}
+
+	gt := &groupTask{name: name, index: -1, task: task}
+	g.tasks[name] = gt
+	g.schedule(gt, d)
+
+	return nil
+}
+
+// SetPeriod changes the period of the named task, a zero or negative value
+// pauses it.
+func (g *Group) SetPeriod(name string, d time.Duration) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	gt, ok := g.tasks[name]
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	if d != gt.period {
+		g.schedule(gt, d)
+	}
+
+	return nil
+}
+
+// Remove drops the named task. A run in flight is not interrupted.
+func (g *Group) Remove(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	gt, ok := g.tasks[name]
+	if !ok {
+		return
+	}
+
+	delete(g.tasks, name)
+	if gt.index >= 0 {
+		heap.Remove(&g.queue, gt.index)
+	}
+}
+
+// Skipped returns the number of ticks dropped across all tasks.
+func (g *Group) Skipped() uint64 {
+	return atomic.LoadUint64(&g.skipped)
+}
+
+// schedule must be called with g.mu held.
+func (g *Group) schedule(gt *groupTask, d time.Duration) {
+	gt.period = d
+
+	if d <= 0 {
+		if gt.index >= 0 {
+			heap.Remove(&g.queue, gt.index)
+		}
+
+		return
+	}
+
+	gt.next = time.Now().Add(d)
+	if gt.index >= 0 {
+		heap.Fix(&g.queue, gt.index)
+	} else {
+		heap.Push(&g.queue, gt)
+	}
+
+	if gt.index == 0 {
+		g.notify()
+	}
+}
+
+func (g *Group) notify() {
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run schedules the tasks until Stop is called or the context is cancelled.
+func (g *Group) Run() {
+	var wg sync.WaitGroup
+	for i := 0; i < g.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.work()
+		}()
+	}
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		g.arm(timer)
+
+		select {
+		case <-g.ctx.Done():
+			g.mu.Lock()
+			g.cond.Broadcast()
+			g.mu.Unlock()
+
+			wg.Wait()
+			return
+		case <-g.wake:
+		case <-timer.C:
+			g.dispatch(time.Now())
+		}
+	}
+}
+
+func (g *Group) Stop() {
+	g.cancel()
+}
+
+func (g *Group) arm(timer *time.Timer) {
+	timer.Stop()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.queue) == 0 {
+		return
+	}
+
+	timer.Reset(time.Until(g.queue[0].next))
+}
+
+// dispatch hands every due task to the workers and moves it to its next tick.
+func (g *Group) dispatch(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for len(g.queue) > 0 && !g.queue[0].next.After(now) {
+		gt := g.queue[0]
+
+		g.submit(gt)
+
+		gt.next = gt.next.Add(gt.period)
+		if !gt.next.After(now) {
+			gt.next = now.Add(gt.period)
+		}
+		heap.Fix(&g.queue, 0)
+	}
+}
+
+// submit must be called with g.mu held.
+func (g *Group) submit(gt *groupTask) {
+	if gt.busy {
+		atomic.AddUint64(&g.skipped, 1)
+		g.logger.Debugf("[dynamicticker] the task %s is still busy, skip the tick", gt.name)
+
+		return
+	}
+
+	gt.busy = true
+	g.ready = append(g.ready, gt)
+	g.cond.Signal()
+}
+
+func (g *Group) work() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for {
+		for len(g.ready) == 0 && g.ctx.Err() == nil {
+			g.cond.Wait()
+		}
+		if g.ctx.Err() != nil {
+			return
+		}
+
+		gt := g.ready[0]
+		g.ready[0] = nil
+		g.ready = g.ready[1:]
+
+		// Tasks removed while waiting for a worker are dropped.
+		if g.tasks[gt.name] == gt {
+			g.mu.Unlock()
+			g.execute(gt)
+			g.mu.Lock()
+		}
+
+		gt.busy = false
+	}
+}
+
+func (g *Group) execute(gt *groupTask) {
+	defer func() {
+		if p := recover(); p != nil {
+			g.logger.Errorf("[dynamicticker] task %s panicked: %v\n%s", gt.name, p, debug.Stack())
+		}
+	}()
+
+	if err := gt.task(g.ctx); err != nil {
+		g.logger.Warnf("[dynamicticker] task %s failed: %v", gt.name, err)
+	}
+}
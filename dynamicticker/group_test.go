@@ -0,0 +1,104 @@
+package dynamicticker
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup_ManyTasks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g := NewGroup(ctx, WithWorkers(4))
+	go g.Run()
+
+	counts := make([]int32, 100)
+	for i := range counts {
+		i := i
+		err := g.Add(fmt.Sprintf("tenant-%d", i), 20*time.Millisecond, func(context.Context) error {
+			atomic.AddInt32(&counts[i], 1)
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	g.Stop()
+
+	for i := range counts {
+		n := atomic.LoadInt32(&counts[i])
+		assert.True(t, n >= 3, "tenant-%d ran %d times", i, n)
+	}
+}
+
+func TestGroup_AddErrors(t *testing.T) {
+	g := NewGroup(context.Background())
+	defer g.Stop()
+
+	task := func(context.Context) error { return nil }
+
+	assert.Equal(t, ErrTaskNameIsEmpty, g.Add("", time.Second, task))
+	assert.NoError(t, g.Add("a", time.Second, task))
+	assert.Equal(t, ErrTaskDuplicatedName, g.Add("a", time.Second, task))
+	assert.Equal(t, ErrTaskNotFound, g.SetPeriod("b", time.Second))
+}
+
+func TestGroup_SetPeriodAndRemove(t *testing.T) {
+	var fast, slow int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g := NewGroup(ctx)
+	go g.Run()
+
+	assert.NoError(t, g.Add("fast", time.Hour, func(context.Context) error {
+		atomic.AddInt32(&fast, 1)
+		return nil
+	}))
+	assert.NoError(t, g.Add("slow", 20*time.Millisecond, func(context.Context) error {
+		atomic.AddInt32(&slow, 1)
+		return nil
+	}))
+
+	assert.NoError(t, g.SetPeriod("fast", 10*time.Millisecond))
+	time.Sleep(65 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&fast) >= 3)
+
+	g.Remove("slow")
+	removed := atomic.LoadInt32(&slow)
+	assert.NoError(t, g.SetPeriod("fast", 0))
+	paused := atomic.LoadInt32(&fast)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, removed, atomic.LoadInt32(&slow))
+	assert.Equal(t, paused, atomic.LoadInt32(&fast))
+}
+
+func TestGroup_NoSelfOverlap(t *testing.T) {
+	var running, peak int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g := NewGroup(ctx, WithWorkers(4))
+	go g.Run()
+
+	assert.NoError(t, g.Add("slow", 5*time.Millisecond, func(context.Context) error {
+		n := atomic.AddInt32(&running, 1)
+		if n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		time.Sleep(30 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}))
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak))
+	assert.True(t, g.Skipped() > 0)
+}
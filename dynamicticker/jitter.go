@@ -0,0 +1,57 @@
+package dynamicticker
+
+import (
+	"math/rand"
+	"time"
+)
+
+type JitterMode int
+
+const (
+	// JitterUniform moves every tick by a random amount within ±spread, the
+	// average period stays unchanged.
+	JitterUniform JitterMode = iota
+	// JitterFull moves every tick earlier by a random amount within spread,
+	// with a spread of the whole period this is the classic full jitter.
+	JitterFull
+)
+
+// Jitter randomizes the tick times of a ticker. The spread is Amount when
+// set, otherwise Fraction of the current period, and never exceeds the period.
+type Jitter struct {
+	Amount   time.Duration
+	Fraction float64
+	Mode     JitterMode
+}
+
+func WithJitter(j Jitter) Option { return func(o *Options) { o.jitter = j } }
+
+// WithRand sets the randomness source of the jitter, e.g. a seeded one for
+// reproducible tests. It is only used from the Run loop.
+func WithRand(r *rand.Rand) Option { return func(o *Options) { o.rand = r } }
+
+func (j Jitter) spread(period time.Duration) time.Duration {
+	spread := j.Amount
+	if spread <= 0 {
+		spread = time.Duration(j.Fraction * float64(period))
+	}
+	if spread > period {
+		spread = period
+	}
+
+	return spread
+}
+
+// offset returns how far the tick due at the unjittered deadline moves.
+func (j Jitter) offset(period time.Duration, r *rand.Rand) time.Duration {
+	spread := j.spread(period)
+	if spread <= 0 {
+		return 0
+	}
+
+	if j.Mode == JitterFull {
+		return -time.Duration(r.Int63n(int64(spread) + 1))
+	}
+
+	return time.Duration(r.Int63n(2*int64(spread)+1)) - spread
+}
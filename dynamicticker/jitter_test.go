@@ -0,0 +1,75 @@
+package dynamicticker
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitter_Offset(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	period := 100 * time.Millisecond
+
+	uniform := Jitter{Fraction: 0.2}
+	full := Jitter{Amount: 30 * time.Millisecond, Mode: JitterFull}
+	capped := Jitter{Amount: time.Second, Mode: JitterFull}
+
+	var sawEarly, sawLate bool
+	for i := 0; i < 1000; i++ {
+		o := uniform.offset(period, r)
+		assert.True(t, o >= -20*time.Millisecond && o <= 20*time.Millisecond)
+		sawEarly = sawEarly || o < 0
+		sawLate = sawLate || o > 0
+
+		o = full.offset(period, r)
+		assert.True(t, o >= -30*time.Millisecond && o <= 0)
+
+		o = capped.offset(period, r)
+		assert.True(t, o >= -period && o <= 0)
+	}
+
+	assert.True(t, sawEarly && sawLate)
+	assert.Equal(t, time.Duration(0), Jitter{}.offset(period, r))
+}
+
+func TestJitter_Reproducible(t *testing.T) {
+	j := Jitter{Fraction: 0.5}
+	a, b := rand.New(rand.NewSource(42)), rand.New(rand.NewSource(42))
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, j.offset(time.Second, a), j.offset(time.Second, b))
+	}
+}
+
+func TestDynamicTicker_Jitter(t *testing.T) {
+	var mu sync.Mutex
+	var ticks []time.Time
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 20*time.Millisecond, func() {
+		mu.Lock()
+		ticks = append(ticks, time.Now())
+		mu.Unlock()
+	}, WithJitter(Jitter{Fraction: 0.5}), WithRand(rand.New(rand.NewSource(7))))
+
+	go dt.Run()
+	time.Sleep(300 * time.Millisecond)
+	dt.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// The jitter does not drift: the average period stays close to 20ms.
+	assert.True(t, len(ticks) >= 11 && len(ticks) <= 18, "got %d ticks", len(ticks))
+
+	distinct := map[time.Duration]bool{}
+	for i := 1; i < len(ticks); i++ {
+		distinct[ticks[i].Sub(ticks[i-1]).Round(2*time.Millisecond)] = true
+	}
+	assert.True(t, len(distinct) > 2, "intervals should vary")
+}
@@ -0,0 +1,117 @@
+package dynamicticker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDynamicTicker_SetPeriodNeverBlocks(t *testing.T) {
+	dt := NewDynamicTicker(context.Background(), 20*time.Millisecond, func() {})
+
+	done := make(chan struct{})
+	go func() {
+		dt.SetPeriod(30 * time.Millisecond)
+		dt.SetPeriod(40 * time.Millisecond)
+		dt.Stop()
+		dt.SetPeriod(50 * time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("SetPeriod blocked")
+	}
+
+	assert.Equal(t, 50*time.Millisecond, dt.Period())
+}
+
+func TestDynamicTicker_SetPeriodLatestWins(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, time.Hour, func() {
+		atomic.AddInt32(&count, 1)
+	})
+	dt.SetPeriod(time.Minute)
+	dt.SetPeriod(20 * time.Millisecond)
+
+	go dt.Run()
+	time.Sleep(70 * time.Millisecond)
+
+	assert.True(t, atomic.LoadInt32(&count) >= 2)
+}
+
+func TestDynamicTicker_RunOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, time.Hour, func() {})
+	go dt.Run()
+	time.Sleep(10 * time.Millisecond)
+
+	returned := make(chan struct{})
+	go func() {
+		dt.Run()
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("second Run did not return")
+	}
+}
+
+func TestDynamicTicker_WaitForInflight(t *testing.T) {
+	var finished int32
+
+	dt := NewDynamicTicker(context.Background(), 10*time.Millisecond, func() {
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+	})
+
+	go dt.Run()
+	time.Sleep(20 * time.Millisecond)
+
+	dt.Stop()
+	dt.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&finished))
+}
+
+func TestDynamicTicker_WaitWithoutRun(t *testing.T) {
+	dt := NewDynamicTicker(context.Background(), 10*time.Millisecond, func() {})
+	dt.Stop()
+
+	select {
+	case <-dt.Done():
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Done was not closed after Stop")
+	}
+}
+
+func TestDynamicTicker_RunAfterStop(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+
+	dt := NewDynamicTicker(context.Background(), time.Millisecond, func() {
+		t.Fatal("the task ran after Stop")
+	}, WithLogger(zap.New(core).Sugar()))
+	dt.Stop()
+
+	assert.Equal(t, ReasonCancelled, dt.Run())
+	assert.Equal(t, 0, logs.Len())
+	assert.Equal(t, StateStopped, dt.State())
+
+	dt = NewDynamicTicker(context.Background(), time.Millisecond, func() {}, WithMaxRuns(1))
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+	dt.Stop()
+	assert.Equal(t, ReasonAlreadyRunning, dt.Run())
+}
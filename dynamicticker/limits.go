@@ -0,0 +1,68 @@
+package dynamicticker
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDone can be returned by the task of NewDynamicTickerCtx to report that
+// its work is complete, like ResultDone.
+var ErrDone = errors.New("dynamicticker: done")
+
+// StopReason tells why Run returned.
+type StopReason int
+
+const (
+	// ReasonCancelled means Stop was called or the context was cancelled.
+	ReasonCancelled StopReason = iota
+	// ReasonCompleted means the task reported ResultDone or ErrDone.
+	ReasonCompleted
+	// ReasonDeadline means the deadline set by WithDeadline passed.
+	ReasonDeadline
+	// ReasonMaxRuns means the runs allowed by WithMaxRuns have finished.
+	ReasonMaxRuns
+	// ReasonAlreadyRunning means Run had already been called.
+	ReasonAlreadyRunning
+	// ReasonCrashed means the supervisor gave up after too many crashes.
+	ReasonCrashed
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case ReasonCancelled:
+		return "cancelled"
+	case ReasonCompleted:
+		return "completed"
+	case ReasonDeadline:
+		return "deadline"
+	case ReasonMaxRuns:
+		return "max runs"
+	case ReasonAlreadyRunning:
+		return "already running"
+	case ReasonCrashed:
+		return "crashed"
+	default:
+		return "unknown"
+	}
+}
+
+// WithMaxRuns stops the ticker once the task has run n times.
+func WithMaxRuns(n int) Option { return func(o *Options) { o.maxRuns = n } }
+
+// WithDeadline stops the ticker at t. Runs in flight see their context
+// cancelled.
+func WithDeadline(t time.Time) Option { return func(o *Options) { o.deadline = t } }
+
+func (r *runner) exhausted() bool {
+	return r.dt.maxRuns > 0 && r.runs >= r.dt.maxRuns
+}
+
+func (r *runner) deadlineC() (<-chan time.Time, func()) {
+	if r.dt.deadline.IsZero() {
+		return nil, func() {}
+	}
+
+	t := time.NewTimer(time.Until(r.dt.deadline))
+
+	return t.C, func() { t.Stop() }
+}
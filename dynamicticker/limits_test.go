@@ -0,0 +1,66 @@
+package dynamicticker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicTicker_MaxRuns(t *testing.T) {
+	var count int32
+
+	dt := NewDynamicTicker(context.Background(), 10*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+	}, WithMaxRuns(3))
+
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	assert.Equal(t, StateStopped, dt.State())
+}
+
+func TestDynamicTicker_Deadline(t *testing.T) {
+	dt := NewDynamicTicker(context.Background(), 10*time.Millisecond, func() {},
+		WithDeadline(time.Now().Add(50*time.Millisecond)))
+
+	start := time.Now()
+	assert.Equal(t, ReasonDeadline, dt.Run())
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+}
+
+func TestDynamicTicker_Completed(t *testing.T) {
+	var count int32
+
+	dt := NewAdaptiveTicker(context.Background(), 10*time.Millisecond, func() Result {
+		if atomic.AddInt32(&count, 1) == 3 {
+			return ResultDone
+		}
+		return ResultNone
+	})
+
+	assert.Equal(t, ReasonCompleted, dt.Run())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestDynamicTicker_CompletedWithErrDone(t *testing.T) {
+	dt := NewDynamicTickerCtx(context.Background(), 10*time.Millisecond, func(context.Context) error {
+		return ErrDone
+	})
+
+	assert.Equal(t, ReasonCompleted, dt.Run())
+}
+
+func TestDynamicTicker_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	dt := NewDynamicTicker(ctx, 10*time.Millisecond, func() {})
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		cancel()
+	}()
+
+	assert.Equal(t, ReasonCancelled, dt.Run())
+	assert.Equal(t, ReasonAlreadyRunning, dt.Run())
+}
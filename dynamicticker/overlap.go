@@ -0,0 +1,39 @@
+package dynamicticker
+
+import "time"
+
+// OverlapPolicy decides what happens to a tick that fires while the task is
+// still running.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip drops the tick and counts it as skipped.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapCatchUp remembers the missed ticks and runs the task back to
+	// back until it has caught up. Pausing the ticker forgets them.
+	OverlapCatchUp
+	// OverlapConcurrent starts another instance of the task, up to the limit
+	// set by WithMaxConcurrent, and skips the tick beyond that.
+	OverlapConcurrent
+)
+
+func WithOverlapPolicy(p OverlapPolicy) Option { return func(o *Options) { o.overlap = p } }
+
+// WithMaxConcurrent bounds the running instances under OverlapConcurrent.
+func WithMaxConcurrent(n int) Option { return func(o *Options) { o.maxConcurrent = n } }
+
+type runResult struct {
+	result  Result
+	err     error
+	panic   any
+	start   time.Time
+	elapsed time.Duration
+}
+
+func (o Options) maxInFlight() int {
+	if o.overlap == OverlapConcurrent && o.maxConcurrent > 1 {
+		return o.maxConcurrent
+	}
+
+	return 1
+}
@@ -0,0 +1,94 @@
+package dynamicticker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicTicker_OverlapSkip(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 20*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+		time.Sleep(70 * time.Millisecond)
+	})
+
+	go dt.Run()
+	time.Sleep(200 * time.Millisecond)
+
+	assert.True(t, atomic.LoadInt32(&count) <= 3, "expected overlapping ticks to be skipped, got %d runs", atomic.LoadInt32(&count))
+	assert.True(t, dt.Skipped() >= 3, "expected skipped ticks, got %d", dt.Skipped())
+}
+
+func TestDynamicTicker_OverlapCatchUp(t *testing.T) {
+	var count int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 20*time.Millisecond, func() {
+		if atomic.AddInt32(&count, 1) == 1 {
+			time.Sleep(110 * time.Millisecond)
+		}
+	}, WithOverlapPolicy(OverlapCatchUp))
+
+	go dt.Run()
+	time.Sleep(150 * time.Millisecond)
+
+	// 5 ticks fired during the slow first run, they all run right after it.
+	assert.True(t, atomic.LoadInt32(&count) >= 6, "expected missed ticks to catch up, got %d runs", atomic.LoadInt32(&count))
+	assert.Equal(t, uint64(0), dt.Skipped())
+}
+
+func TestDynamicTicker_OverlapConcurrent(t *testing.T) {
+	var running, peak int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 10*time.Millisecond, func() {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(60 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}, WithOverlapPolicy(OverlapConcurrent), WithMaxConcurrent(3))
+
+	go dt.Run()
+	time.Sleep(200 * time.Millisecond)
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&peak))
+	assert.True(t, dt.Skipped() > 0)
+}
+
+func TestDynamicTicker_StopWhileRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dt := NewDynamicTicker(ctx, 10*time.Millisecond, func() {
+		time.Sleep(time.Second)
+	})
+
+	returned := make(chan struct{})
+	go func() {
+		dt.Run()
+		close(returned)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	dt.Stop()
+
+	select {
+	case <-returned:
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Run did not return while the task was running")
+	}
+}
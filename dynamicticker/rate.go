@@ -0,0 +1,226 @@
+package dynamicticker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate is how often a ticker fires, Count times every Per. The zero Rate
+// means paused.
+//
+// ParseRate accepts the following grammar, surrounding spaces are ignored and
+// keywords and units are case-insensitive:
+//
+//	spec     = "0" | rate | every | duration
+//	rate     = count "/" ( unit | duration )
+//	every    = "every" spaces duration
+//	count    = positive integer
+//	unit     = "s" | "sec" | "second" | "min" | "minute" | "h" | "hr" | "hour" | "d" | "day"
+//	duration = Go duration, e.g. "2h30m" or "500ms"
+//
+// e.g. "10/s", "5/min", "3/2h", "every 30s", "2h30m" and "0". A zero duration
+// pauses the ticker too. A rate must leave at least a nanosecond between two
+// ticks.
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+// SpecError reports an invalid spec and the byte offset of the problem.
+type SpecError struct {
+	Spec string
+	Pos  int
+	Msg  string
+}
+
+func (e *SpecError) Error() string {
+	return fmt.Sprintf("dynamicticker: invalid spec %q at position %d: %s", e.Spec, e.Pos, e.Msg)
+}
+
+var rateUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+}
+
+var canonicalUnits = []struct {
+	name string
+	d    time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"min", time.Minute},
+	{"s", time.Second},
+}
+
+func ParseRate(spec string) (Rate, error) {
+	start := len(spec) - len(strings.TrimLeft(spec, " \t"))
+	body := strings.TrimRight(spec[start:], " \t")
+
+	fail := func(pos int, format string, v ...any) (Rate, error) {
+		return Rate{}, &SpecError{Spec: spec, Pos: start + pos, Msg: fmt.Sprintf(format, v...)}
+	}
+
+	if body == "" {
+		return fail(0, "empty spec")
+	}
+	if body == "0" {
+		return Rate{}, nil
+	}
+
+	if len(body) > 5 && strings.EqualFold(body[:5], "every") && (body[5] == ' ' || body[5] == '\t') {
+		pos := 5 + len(body[5:]) - len(strings.TrimLeft(body[5:], " \t"))
+
+		d, errPos, msg := parseSpecDuration(body[pos:])
+		if msg != "" {
+			return fail(pos+errPos, msg)
+		}
+		if d <= 0 {
+			return fail(pos, "the period must be positive")
+		}
+
+		return Rate{Count: 1, Per: d}, nil
+	}
+
+	if slash := strings.IndexByte(body, '/'); slash >= 0 {
+		// Atoi accepts a sign, the grammar does not.
+		count, err := strconv.Atoi(body[:slash])
+		if err != nil || count <= 0 || body[0] == '+' {
+			return fail(0, "expected a positive count before '/'")
+		}
+
+		unit := body[slash+1:]
+		if unit == "" {
+			return fail(slash+1, "expected a unit or a duration after '/'")
+		}
+
+		per, ok := rateUnits[strings.ToLower(unit)]
+		if !ok {
+			if c := unit[0]; c < '0' || c > '9' {
+				return fail(slash+1, "unknown unit %q", unit)
+			}
+
+			d, errPos, msg := parseSpecDuration(unit)
+			if msg != "" {
+				return fail(slash+1+errPos, msg)
+			}
+			if d <= 0 {
+				return fail(slash+1, "the duration must be positive")
+			}
+			per = d
+		}
+
+		// The period would round down to zero and pause the ticker.
+		if per/time.Duration(count) == 0 {
+			return fail(0, "more than one tick per nanosecond")
+		}
+
+		return Rate{Count: count, Per: per}, nil
+	}
+
+	d, errPos, msg := parseSpecDuration(body)
+	if msg != "" {
+		return fail(errPos, msg)
+	}
+	if d == 0 {
+		return Rate{}, nil
+	}
+
+	return Rate{Count: 1, Per: d}, nil
+}
+
+// parseSpecDuration wraps time.ParseDuration, locating the first offending
+// byte on failure.
+func parseSpecDuration(s string) (time.Duration, int, string) {
+	if strings.HasPrefix(s, "-") {
+		return 0, 0, "negative duration"
+	}
+
+	d, err := time.ParseDuration(s)
+	if err == nil {
+		return d, 0, ""
+	}
+
+	i := 0
+	for i < len(s) {
+		numStart := i
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+			i++
+		}
+		if i == numStart {
+			return 0, i, "expected a number"
+		}
+
+		unitStart := i
+		for i < len(s) && !(s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+			i++
+		}
+		if i == unitStart {
+			return 0, i, "missing unit in duration"
+		}
+
+		switch s[unitStart:i] {
+		case "ns", "us", "µs", "μs", "ms", "s", "m", "h":
+		default:
+			return 0, unitStart, fmt.Sprintf("unknown unit %q in duration", s[unitStart:i])
+		}
+	}
+
+	return 0, 0, err.Error()
+}
+
+func (r Rate) Paused() bool { return r.Count <= 0 || r.Per <= 0 }
+
+// Period returns the interval between two ticks, zero when paused.
+func (r Rate) Period() time.Duration {
+	if r.Paused() {
+		return 0
+	}
+
+	return r.Per / time.Duration(r.Count)
+}
+
+// String returns the canonical spec of r, which ParseRate turns back into r.
+func (r Rate) String() string {
+	if r.Paused() {
+		return "0"
+	}
+	if r.Count == 1 {
+		return "every " + r.Per.String()
+	}
+
+	for _, u := range canonicalUnits {
+		if r.Per == u.d {
+			return fmt.Sprintf("%d/%s", r.Count, u.name)
+		}
+	}
+
+	return fmt.Sprintf("%d/%s", r.Count, r.Per)
+}
+
+// NewFromSpec creates a ticker whose period is given as a rate spec, see Rate
+// for the grammar.
+func NewFromSpec(ctx context.Context, spec string, task func(), opts ...Option) (*DynamicTicker, error) {
+	r, err := ParseRate(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewDynamicTicker(ctx, r.Period(), task, opts...), nil
+}
+
+// SetSpec is SetPeriod taking a rate spec, see Rate for the grammar.
+func (dt *DynamicTicker) SetSpec(spec string) error {
+	r, err := ParseRate(spec)
+	if err != nil {
+		return err
+	}
+
+	dt.SetPeriod(r.Period())
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package dynamicticker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRate(t *testing.T) {
+	tables := []struct {
+		spec      string
+		period    time.Duration
+		canonical string
+	}{
+		{spec: "0", period: 0, canonical: "0"},
+		{spec: " 0s ", period: 0, canonical: "0"},
+		{spec: "10/s", period: 100 * time.Millisecond, canonical: "10/s"},
+		{spec: "5/min", period: 12 * time.Second, canonical: "5/min"},
+		{spec: "5/Minute", period: 12 * time.Second, canonical: "5/min"},
+		{spec: "3/2h", period: 40 * time.Minute, canonical: "3/2h0m0s"},
+		{spec: "2/day", period: 12 * time.Hour, canonical: "2/d"},
+		{spec: "1/min", period: time.Minute, canonical: "every 1m0s"},
+		{spec: "every 30s", period: 30 * time.Second, canonical: "every 30s"},
+		{spec: "EVERY  500ms", period: 500 * time.Millisecond, canonical: "every 500ms"},
+		{spec: "2h30m", period: 150 * time.Minute, canonical: "every 2h30m0s"},
+	}
+
+	for _, table := range tables {
+		r, err := ParseRate(table.spec)
+		assert.NoError(t, err, table.spec)
+		assert.Equal(t, table.period, r.Period(), table.spec)
+		assert.Equal(t, table.canonical, r.String(), table.spec)
+
+		back, err := ParseRate(r.String())
+		assert.NoError(t, err, table.spec)
+		assert.Equal(t, r, back, table.spec)
+	}
+}
+
+func TestParseRate_Errors(t *testing.T) {
+	tables := []struct {
+		spec string
+		pos  int
+	}{
+		{spec: "", pos: 0},
+		{spec: "abc", pos: 0},
+		{spec: "x/min", pos: 0},
+		{spec: "0/min", pos: 0},
+		{spec: "5/", pos: 2},
+		{spec: "5/fortnight", pos: 2},
+		{spec: "5/1fortnight", pos: 3},
+		{spec: "every 30x", pos: 8},
+		{spec: "  2h30", pos: 6},
+		{spec: "2h3y", pos: 3},
+		{spec: "-5s", pos: 0},
+		{spec: "every 0s", pos: 6},
+		{spec: "+5/s", pos: 0},
+		{spec: "3/1ns", pos: 0},
+		{spec: "2000000000/s", pos: 0},
+	}
+
+	for _, table := range tables {
+		_, err := ParseRate(table.spec)
+
+		var specErr *SpecError
+		if assert.True(t, errors.As(err, &specErr), "spec %q: %v", table.spec, err) {
+			assert.Equal(t, table.pos, specErr.Pos, "spec %q: %v", table.spec, err)
+		}
+	}
+}
+
+func TestDynamicTicker_Spec(t *testing.T) {
+	_, err := NewFromSpec(context.Background(), "5/fortnight", func() {})
+	assert.Error(t, err)
+
+	dt, err := NewFromSpec(context.Background(), "4/s", func() {})
+	assert.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, dt.Period())
+
+	assert.NoError(t, dt.SetSpec("every 2s"))
+	assert.Equal(t, 2*time.Second, dt.Period())
+
+	assert.Error(t, dt.SetSpec("often"))
+	assert.Equal(t, 2*time.Second, dt.Period())
+}
@@ -0,0 +1,275 @@
+package dynamicticker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// runner holds the state owned by the Run loop.
+type runner struct {
+	dt *DynamicTicker
+
+	timer *time.Timer
+	// next is the deadline of the upcoming tick, zero while paused. It is base
+	// moved by the jitter.
+	next time.Time
+	base time.Time
+	// applied is the period the schedule runs at, it differs from dt.cur
+	// while backing off after failures.
+	applied time.Duration
+
+	failures int
+	running  int
+	pending  int
+	runs     int
+	// resumed is set on a runner taking over from one that crashed.
+	resumed bool
+
+	// Every in-flight run owns a slot in finished, so runs that end after
+	// Run returned never block.
+	finished chan runResult
+}
+
+func newRunner(dt *DynamicTicker) *runner {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	return &runner{
+		dt:       dt,
+		timer:    timer,
+		finished: make(chan runResult, dt.maxInFlight),
+	}
+}
+
+// takeOver restarts from the runner prev that crashed. Its runs keep counting
+// towards WithMaxRuns, and its in-flight runs towards the overlap limits until
+// they report to r. The schedule and the error backoff go on where prev left
+// them.
+func (r *runner) takeOver(prev *runner) {
+	r.runs = prev.runs
+	r.running = prev.running
+	r.pending = prev.pending
+	r.finished = prev.finished
+
+	r.failures = prev.failures
+	r.applied = prev.applied
+	r.base = prev.base
+	r.next = prev.next
+	r.resumed = true
+	r.arm()
+}
+
+func (r *runner) run() StopReason {
+	defer r.timer.Stop()
+
+	dt := r.dt
+
+	deadlineC, stopDeadline := r.deadlineC()
+	defer stopDeadline()
+
+	r.applyPeriod()
+
+	if dt.runOnStart && !r.resumed {
+		r.tick()
+	}
+
+	for {
+		select {
+		case <-dt.ctx.Done():
+			return ReasonCancelled
+
+		case <-deadlineC:
+			return ReasonDeadline
+
+		case <-dt.wake:
+			r.applyPeriod()
+
+		case <-dt.trigger:
+			r.tick()
+
+		case <-r.timerC():
+			now := time.Now()
+			// Timer fires can be stale or, for aligned ticks, early on purpose.
+			if now.Before(r.next) {
+				r.arm()
+				continue
+			}
+
+			r.tick()
+
+			r.base = dt.nextTick(r.base, now, r.applied)
+			r.next = r.base.Add(dt.jitter.offset(r.applied, dt.rand))
+			r.arm()
+
+		case res := <-r.finished:
+			r.finish(res)
+
+			if res.result == ResultDone {
+				return ReasonCompleted
+			}
+			if r.exhausted() && r.running == 0 {
+				return ReasonMaxRuns
+			}
+		}
+	}
+}
+
+func (r *runner) tick() {
+	switch {
+	case r.exhausted():
+	case !r.dt.allow():
+		atomic.AddUint64(&r.dt.gated, 1)
+	case r.running < r.dt.maxInFlight:
+		r.start()
+	case r.dt.overlap == OverlapCatchUp:
+		r.pending++
+	default:
+		atomic.AddUint64(&r.dt.skipped, 1)
+	}
+}
+
+func (r *runner) start() {
+	r.runs++
+	r.running++
+	r.dt.inflight.Add(1)
+
+	go func() {
+		defer r.dt.inflight.Done()
+
+		r.finished <- r.dt.runTask()
+	}()
+}
+
+func (r *runner) finish(res runResult) {
+	dt := r.dt
+	r.running--
+
+	if res.err != nil {
+		r.failures++
+		dt.logger.Warnf("[dynamicticker] task failed %d time(s) in a row: %v", r.failures, res.err)
+	} else {
+		r.failures = 0
+	}
+
+	dt.recordFinish(res, r.failures)
+
+	if res.panic != nil && dt.supervisor != nil {
+		panic(&taskPanic{value: res.panic})
+	}
+
+	if d := dt.adapt(res.result, res.elapsed); d > 0 && !r.next.IsZero() {
+		r.reschedule(dt.backoff(d, r.failures))
+	}
+
+	if r.pending > 0 && !r.exhausted() {
+		r.pending--
+		r.start()
+	}
+}
+
+// applyPeriod picks up the period and pause state set from outside the loop.
+func (r *runner) applyPeriod() {
+	r.dt.mu.Lock()
+	d := r.dt.effectivePeriod()
+	restart := r.dt.restart
+	r.dt.restart = false
+	r.dt.mu.Unlock()
+
+	if d <= 0 {
+		r.pending = 0
+	}
+
+	d = r.dt.backoff(d, r.failures)
+	if restart && d > 0 && d == r.applied {
+		r.restartPhase(d)
+		return
+	}
+
+	r.reschedule(d)
+}
+
+// reschedule switches the schedule to period d, pausing it for d <= 0. The
+// phase restarts only when the period actually changes.
+func (r *runner) reschedule(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	if d != r.applied {
+		r.dt.recordPeriod(r.applied, d)
+	}
+
+	if d == 0 {
+		r.next = time.Time{}
+		r.base = time.Time{}
+		r.applied = 0
+		r.arm()
+
+		return
+	}
+
+	if d == r.applied {
+		return
+	}
+
+	r.restartPhase(d)
+}
+
+// restartPhase counts the next tick of period d from now.
+func (r *runner) restartPhase(d time.Duration) {
+	r.applied = d
+	r.base = r.dt.firstTick(time.Now(), d)
+	r.next = r.base.Add(r.dt.jitter.offset(d, r.dt.rand))
+	r.arm()
+}
+
+func (r *runner) arm() {
+	r.timer.Stop()
+
+	if r.next.IsZero() {
+		return
+	}
+
+	wait := time.Until(r.next)
+	if r.dt.alignment != nil && wait > alignCheckInterval {
+		wait = alignCheckInterval
+	}
+
+	r.timer.Reset(wait)
+}
+
+func (r *runner) timerC() <-chan time.Time {
+	if r.next.IsZero() {
+		return nil
+	}
+
+	return r.timer.C
+}
+
+// firstTick returns the first deadline after switching to period d at now.
+func (dt *DynamicTicker) firstTick(now time.Time, d time.Duration) time.Time {
+	if dt.alignment != nil {
+		return dt.alignment.next(now, d)
+	}
+
+	return now.Add(d)
+}
+
+// nextTick returns the deadline following the tick due at prev that fired at
+// now, which is before prev when the jitter moved the tick earlier. Like
+// time.Ticker it keeps the phase and drops ticks it fell behind on.
+func (dt *DynamicTicker) nextTick(prev, now time.Time, d time.Duration) time.Time {
+	if dt.alignment != nil {
+		if now.Before(prev) {
+			return dt.alignment.next(prev, d)
+		}
+
+		return dt.alignment.next(now, d)
+	}
+
+	next := prev.Add(d)
+	if !next.After(now) {
+		next = now.Add(d)
+	}
+
+	return next
+}
@@ -0,0 +1,286 @@
+package dynamicticker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pqiaohaoq/gotools/log"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrPeriodKeyNotFound = errors.New("period key not found")
+	ErrInvalidPeriod     = errors.New("invalid period")
+)
+
+// PeriodSource is a live configuration value that drives the period of a
+// ticker bound to it with WithPeriodSource.
+type PeriodSource interface {
+	// Watch calls apply with the current period and again on every change
+	// until ctx is done.
+	Watch(ctx context.Context, apply func(d time.Duration))
+}
+
+// WithPeriodSource makes Run follow the period published by src.
+func WithPeriodSource(src PeriodSource) Option { return func(o *Options) { o.periodSource = src } }
+
+// WithPeriodBounds clamps the positive periods coming from the period source
+// into [min, max]. Zero disables a bound, zero and negative periods still
+// pause the ticker.
+func WithPeriodBounds(min, max time.Duration) Option {
+	return func(o *Options) {
+		o.minPeriod = min
+		o.maxPeriod = max
+	}
+}
+
+func (dt *DynamicTicker) applySourcePeriod(d time.Duration) {
+	if d > 0 {
+		d = clamp(d, dt.minPeriod, dt.maxPeriod)
+	}
+
+	dt.logger.Debugf("[dynamicticker] the period source publishes the period %s", d)
+
+	dt.SetPeriod(d)
+}
+
+// AtomicPeriod is an in-process PeriodSource. It also serves as an admin HTTP
+// handler: GET returns the period, PUT and POST set it from the "period" form
+// value or from the plain request body.
+type AtomicPeriod struct {
+	mu   sync.Mutex
+	d    time.Duration
+	subs map[chan struct{}]struct{}
+}
+
+func NewAtomicPeriod(d time.Duration) *AtomicPeriod {
+	return &AtomicPeriod{d: d, subs: make(map[chan struct{}]struct{})}
+}
+
+func (a *AtomicPeriod) Get() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.d
+}
+
+func (a *AtomicPeriod) Set(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if d == a.d {
+		return
+	}
+
+	a.d = d
+	for ch := range a.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *AtomicPeriod) Watch(ctx context.Context, apply func(d time.Duration)) {
+	ch := make(chan struct{}, 1)
+
+	a.mu.Lock()
+	a.subs[ch] = struct{}{}
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		delete(a.subs, ch)
+		a.mu.Unlock()
+	}()
+
+	apply(a.Get())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			apply(a.Get())
+		}
+	}
+}
+
+type periodBody struct {
+	Period string `json:"period"`
+}
+
+func (a *AtomicPeriod) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		d, err := readPeriod(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		a.Set(d)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(periodBody{Period: a.Get().String()})
+}
+
+func readPeriod(r *http.Request) (time.Duration, error) {
+	s := r.FormValue("period")
+	if s == "" {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+		if err != nil {
+			return 0, err
+		}
+
+		var pb periodBody
+		if json.Unmarshal(body, &pb) == nil {
+			s = pb.Period
+		} else {
+			s = string(bytes.TrimSpace(body))
+		}
+	}
+
+	return parsePeriodValue(s)
+}
+
+// FileSource publishes the period stored under Key in a JSON or YAML file,
+// picked by the file extension. Key is a dot separated path such as
+// "poller.interval" and its value is either a rate spec like "30s" or
+// "5/min", see Rate, or a non-negative number of seconds. The file is polled
+// for changes every Interval, one second by default, and invalid contents are
+// logged and ignored.
+type FileSource struct {
+	Path     string
+	Key      string
+	Interval time.Duration
+	Logger   log.Logger
+}
+
+func (fs *FileSource) Watch(ctx context.Context, apply func(d time.Duration)) {
+	logger := fs.Logger
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+
+	interval := fs.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	var (
+		modTime time.Time
+		size    int64
+		last    time.Duration
+		applied bool
+	)
+
+	check := func() {
+		fi, err := os.Stat(fs.Path)
+		if err != nil {
+			logger.Warnf("[dynamicticker] stat the period file %s: %v", fs.Path, err)
+			return
+		}
+		if fi.ModTime().Equal(modTime) && fi.Size() == size {
+			return
+		}
+		modTime, size = fi.ModTime(), fi.Size()
+
+		d, err := fs.read()
+		if err != nil {
+			logger.Warnf("[dynamicticker] read the period from %s: %v", fs.Path, err)
+			return
+		}
+
+		if !applied || d != last {
+			applied, last = true, d
+			apply(d)
+		}
+	}
+
+	check()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+func (fs *FileSource) read() (time.Duration, error) {
+	data, err := os.ReadFile(fs.Path)
+	if err != nil {
+		return 0, err
+	}
+
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(fs.Path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var v any = doc
+	for _, part := range strings.Split(fs.Key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrPeriodKeyNotFound, fs.Key)
+		}
+		if v, ok = m[part]; !ok {
+			return 0, fmt.Errorf("%w: %s", ErrPeriodKeyNotFound, fs.Key)
+		}
+	}
+
+	// Numbers are seconds and, like the specs, must not be negative.
+	switch value := v.(type) {
+	case string:
+		return parsePeriodValue(value)
+	case int:
+		if value >= 0 && int64(value) <= math.MaxInt64/int64(time.Second) {
+			return time.Duration(value) * time.Second, nil
+		}
+	case float64:
+		if value >= 0 && value*float64(time.Second) < math.MaxInt64 {
+			return time.Duration(value * float64(time.Second)), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %v", ErrInvalidPeriod, v)
+}
+
+func parsePeriodValue(s string) (time.Duration, error) {
+	r, err := ParseRate(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidPeriod, err)
+	}
+
+	return r.Period(), nil
+}
@@ -0,0 +1,123 @@
+package dynamicticker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicPeriod_BindTicker(t *testing.T) {
+	src := NewAtomicPeriod(time.Hour)
+
+	dt := NewDynamicTicker(context.Background(), time.Minute, func() {},
+		WithPeriodSource(src), WithPeriodBounds(10*time.Millisecond, time.Minute))
+	go dt.Run()
+	defer dt.Stop()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, time.Minute, dt.Period(), "the source period should be clamped to max")
+
+	src.Set(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, dt.Period(), "the source period should be clamped to min")
+
+	src.Set(0)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, StatePaused, dt.State())
+}
+
+func TestAtomicPeriod_ServeHTTP(t *testing.T) {
+	src := NewAtomicPeriod(time.Second)
+
+	rec := httptest.NewRecorder()
+	src.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/period", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"period":"1s"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	src.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/period", strings.NewReader(`{"period":"30s"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 30*time.Second, src.Get())
+
+	rec = httptest.NewRecorder()
+	src.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/period?period=2m", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2*time.Minute, src.Get())
+
+	rec = httptest.NewRecorder()
+	src.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/period", strings.NewReader("soon")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, 2*time.Minute, src.Get())
+
+	rec = httptest.NewRecorder()
+	src.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/period", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestFileSource_Read(t *testing.T) {
+	dir := t.TempDir()
+
+	tables := []struct {
+		name    string
+		content string
+		key     string
+		period  time.Duration
+		err     error
+	}{
+		{name: "a.json", content: `{"poller":{"interval":"30s"}}`, key: "poller.interval", period: 30 * time.Second},
+		{name: "b.json", content: `{"interval":1.5}`, key: "interval", period: 1500 * time.Millisecond},
+		{name: "c.yaml", content: "poller:\n  interval: 2m\n", key: "poller.interval", period: 2 * time.Minute},
+		{name: "d.yml", content: "interval: 5\n", key: "interval", period: 5 * time.Second},
+		{name: "e.json", content: `{"poller":{}}`, key: "poller.interval", err: ErrPeriodKeyNotFound},
+		{name: "f.json", content: `{"interval":"soon"}`, key: "interval", err: ErrInvalidPeriod},
+		{name: "g.json", content: `{"interval":-5}`, key: "interval", err: ErrInvalidPeriod},
+		{name: "h.yaml", content: "interval: -5\n", key: "interval", err: ErrInvalidPeriod},
+		{name: "i.yaml", content: "interval: .nan\n", key: "interval", err: ErrInvalidPeriod},
+		{name: "j.yaml", content: "interval: .inf\n", key: "interval", err: ErrInvalidPeriod},
+		{name: "k.json", content: `{"interval":0}`, key: "interval", period: 0},
+	}
+
+	for _, table := range tables {
+		path := filepath.Join(dir, table.name)
+		assert.NoError(t, os.WriteFile(path, []byte(table.content), 0o600))
+
+		d, err := (&FileSource{Path: path, Key: table.key}).read()
+		if table.err != nil {
+			assert.ErrorIs(t, err, table.err, table.name)
+			continue
+		}
+		assert.NoError(t, err, table.name)
+		assert.Equal(t, table.period, d, table.name)
+	}
+}
+
+func TestFileSource_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"interval":"1s"}`), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	periods := make(chan time.Duration, 4)
+	go (&FileSource{Path: path, Key: "interval", Interval: 5 * time.Millisecond}).Watch(ctx, func(d time.Duration) {
+		periods <- d
+	})
+
+	assert.Equal(t, time.Second, <-periods)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"interval":"20s"}`), 0o600))
+
+	select {
+	case d := <-periods:
+		assert.Equal(t, 20*time.Second, d)
+	case <-time.After(time.Second):
+		t.Fatal("the file change was not picked up")
+	}
+}
@@ -0,0 +1,108 @@
+package dynamicticker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the execution history of a ticker.
+type Stats struct {
+	Runs                uint64
+	LastStart           time.Time
+	LastEnd             time.Time
+	LastDuration        time.Duration
+	AvgDuration         time.Duration
+	ConsecutiveFailures int
+	Skipped             uint64
+	// Gated counts the ticks dropped because the gate was closed.
+	Gated uint64
+	// Period is the period the ticker currently runs at, including the error
+	// backoff, zero while paused.
+	Period time.Duration
+	State  State
+}
+
+// RunInfo describes one finished run of the task.
+type RunInfo struct {
+	Start   time.Time
+	Elapsed time.Duration
+	Result  Result
+	Err     error
+}
+
+// The hooks are called from the Run loop and must not block.
+func WithOnTick(f func(info RunInfo)) Option { return func(o *Options) { o.onTick = f } }
+
+// WithOnPeriodChange is called whenever the period the ticker runs at changes,
+// whether through SetPeriod, pausing, a controller or the error backoff.
+func WithOnPeriodChange(f func(old, new time.Duration)) Option {
+	return func(o *Options) { o.onPeriodChange = f }
+}
+
+func WithOnStop(f func(reason StopReason)) Option { return func(o *Options) { o.onStop = f } }
+
+type stats struct {
+	runs          uint64
+	lastStart     time.Time
+	lastEnd       time.Time
+	lastDuration  time.Duration
+	totalDuration time.Duration
+	failures      int
+	applied       time.Duration
+}
+
+func (dt *DynamicTicker) Stats() Stats {
+	state := dt.State()
+
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	s := Stats{
+		Runs:                dt.stats.runs,
+		LastStart:           dt.stats.lastStart,
+		LastEnd:             dt.stats.lastEnd,
+		LastDuration:        dt.stats.lastDuration,
+		ConsecutiveFailures: dt.stats.failures,
+		Skipped:             atomic.LoadUint64(&dt.skipped),
+		Gated:               atomic.LoadUint64(&dt.gated),
+		Period:              dt.stats.applied,
+		State:               state,
+	}
+	if s.Runs > 0 {
+		s.AvgDuration = dt.stats.totalDuration / time.Duration(s.Runs)
+	}
+
+	return s
+}
+
+func (dt *DynamicTicker) recordStart(start time.Time) {
+	dt.mu.Lock()
+	dt.stats.lastStart = start
+	dt.mu.Unlock()
+}
+
+func (dt *DynamicTicker) recordFinish(res runResult, failures int) {
+	dt.mu.Lock()
+	dt.stats.runs++
+	dt.stats.lastEnd = res.start.Add(res.elapsed)
+	dt.stats.lastDuration = res.elapsed
+	dt.stats.totalDuration += res.elapsed
+	dt.stats.failures = failures
+	dt.mu.Unlock()
+
+	if dt.onTick != nil {
+		dt.onTick(RunInfo{Start: res.start, Elapsed: res.elapsed, Result: res.result, Err: res.err})
+	}
+}
+
+func (dt *DynamicTicker) recordPeriod(old, new time.Duration) {
+	dt.mu.Lock()
+	dt.stats.applied = new
+	dt.mu.Unlock()
+
+	if dt.onPeriodChange != nil {
+		dt.onPeriodChange(old, new)
+	}
+
+	dt.logger.Debugf("[dynamicticker] change the period from %s to %s", old, new)
+}
@@ -0,0 +1,75 @@
+package dynamicticker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicTicker_Stats(t *testing.T) {
+	var count int32
+
+	dt := NewDynamicTickerCtx(context.Background(), 10*time.Millisecond, func(context.Context) error {
+		time.Sleep(2 * time.Millisecond)
+		if atomic.AddInt32(&count, 1) > 2 {
+			return errors.New("failed")
+		}
+		return nil
+	}, WithMaxRuns(4))
+
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+
+	s := dt.Stats()
+	assert.Equal(t, uint64(4), s.Runs)
+	assert.Equal(t, 2, s.ConsecutiveFailures)
+	assert.True(t, s.LastDuration >= 2*time.Millisecond)
+	assert.True(t, s.AvgDuration >= 2*time.Millisecond)
+	assert.True(t, s.LastEnd.After(s.LastStart))
+	assert.Equal(t, 10*time.Millisecond, s.Period)
+	assert.Equal(t, StateStopped, s.State)
+}
+
+func TestDynamicTicker_Hooks(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		ticks   int
+		periods [][2]time.Duration
+		reason  StopReason = -1
+	)
+
+	dt := NewAdaptiveTicker(context.Background(), 10*time.Millisecond, func() Result {
+		return ResultIdle
+	},
+		WithController(ExponentialBackoff{Max: 40 * time.Millisecond}),
+		WithMaxRuns(3),
+		WithOnTick(func(RunInfo) {
+			mu.Lock()
+			ticks++
+			mu.Unlock()
+		}),
+		WithOnPeriodChange(func(old, new time.Duration) {
+			mu.Lock()
+			periods = append(periods, [2]time.Duration{old, new})
+			mu.Unlock()
+		}),
+		WithOnStop(func(r StopReason) { reason = r }),
+	)
+
+	dt.Run()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, 3, ticks)
+	assert.Equal(t, ReasonMaxRuns, reason)
+	assert.Equal(t, [][2]time.Duration{
+		{0, 10 * time.Millisecond},
+		{10 * time.Millisecond, 20 * time.Millisecond},
+		{20 * time.Millisecond, 40 * time.Millisecond},
+	}, periods)
+}
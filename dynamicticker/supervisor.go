@@ -0,0 +1,162 @@
+package dynamicticker
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// Supervisor restarts the Run loop after a crash, i.e. a panic of the task or
+// of the loop itself such as in a hook or a controller. Without it a task
+// panic only counts as a failed run and a loop panic unwinds Run.
+type Supervisor struct {
+	// MaxCrashes is the number of crashes within Window tolerated before the
+	// supervisor gives up and Run returns ReasonCrashed, 5 by default.
+	MaxCrashes int
+	// Window is 1 minute by default.
+	Window time.Duration
+	// Backoff is the delay before the first restart, 100ms by default. It
+	// doubles for every further crash within Window, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// WithSupervisor runs the ticker under s, zero fields take their defaults.
+func WithSupervisor(s Supervisor) Option { return func(o *Options) { o.supervisor = &s } }
+
+// Health is the state of a supervised ticker, an unsupervised one is always
+// HealthOK.
+type Health int
+
+const (
+	HealthOK Health = iota
+	// HealthRecovering means the loop crashed and waits to be restarted.
+	HealthRecovering
+	// HealthFailed means the supervisor gave up.
+	HealthFailed
+)
+
+func (h Health) String() string {
+	switch h {
+	case HealthOK:
+		return "ok"
+	case HealthRecovering:
+		return "recovering"
+	case HealthFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Health reports the supervision state for readiness probes.
+func (dt *DynamicTicker) Health() Health {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	return dt.health
+}
+
+func (dt *DynamicTicker) setHealth(h Health) {
+	dt.mu.Lock()
+	dt.health = h
+	dt.mu.Unlock()
+}
+
+// taskPanic carries a task panic from the task goroutine into the supervised
+// loop.
+type taskPanic struct {
+	value any
+}
+
+func (s Supervisor) withDefaults() Supervisor {
+	if s.MaxCrashes <= 0 {
+		s.MaxCrashes = 5
+	}
+	if s.Window <= 0 {
+		s.Window = time.Minute
+	}
+	if s.Backoff <= 0 {
+		s.Backoff = 100 * time.Millisecond
+	}
+	if s.MaxBackoff <= 0 {
+		s.MaxBackoff = 10 * time.Second
+	}
+
+	return s
+}
+
+func (dt *DynamicTicker) supervise() StopReason {
+	s := dt.supervisor.withDefaults()
+
+	var (
+		crashes []time.Time
+		prev    *runner
+	)
+
+	for {
+		r := newRunner(dt)
+		if prev != nil {
+			r.takeOver(prev)
+		}
+		prev = r
+
+		reason, crashed := dt.runRecovered(r)
+		if !crashed {
+			return reason
+		}
+
+		now := time.Now()
+		recent := crashes[:0]
+		for _, t := range crashes {
+			if now.Sub(t) < s.Window {
+				recent = append(recent, t)
+			}
+		}
+		crashes = append(recent, now)
+
+		if len(crashes) > s.MaxCrashes {
+			dt.setHealth(HealthFailed)
+			dt.logger.Errorf("[dynamicticker] the ticker crashed %d times within %s, give up", len(crashes), s.Window)
+
+			return ReasonCrashed
+		}
+
+		delay := s.Backoff << uint(len(crashes)-1)
+		if delay > s.MaxBackoff || delay <= 0 {
+			delay = s.MaxBackoff
+		}
+
+		dt.setHealth(HealthRecovering)
+		dt.logger.Warnf("[dynamicticker] the ticker crashed %d time(s) within %s, restart in %s", len(crashes), s.Window, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-dt.ctx.Done():
+			timer.Stop()
+			return ReasonCancelled
+		case <-timer.C:
+		}
+
+		dt.setHealth(HealthOK)
+	}
+}
+
+func (dt *DynamicTicker) runRecovered(r *runner) (reason StopReason, crashed bool) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+
+		crashed = true
+		if tp, ok := p.(*taskPanic); ok {
+			// runTask already logged the stack of the task.
+			dt.logger.Errorf("[dynamicticker] the task crashed the ticker: %v", tp.value)
+			return
+		}
+
+		dt.logger.Errorf("[dynamicticker] the ticker crashed: %v\n%s", p, debug.Stack())
+	}()
+
+	return r.run(), false
+}
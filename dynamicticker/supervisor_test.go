@@ -0,0 +1,150 @@
+package dynamicticker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDynamicTicker_SupervisorRestart(t *testing.T) {
+	var count int32
+
+	dt := NewDynamicTicker(context.Background(), 5*time.Millisecond, func() {
+		if atomic.AddInt32(&count, 1) == 2 {
+			panic("boom")
+		}
+	}, WithMaxRuns(5), WithSupervisor(Supervisor{Backoff: time.Millisecond}))
+
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+	assert.Equal(t, int32(5), atomic.LoadInt32(&count))
+	assert.Equal(t, HealthOK, dt.Health())
+}
+
+func TestDynamicTicker_SupervisorGiveUp(t *testing.T) {
+	var count int32
+
+	dt := NewDynamicTicker(context.Background(), 2*time.Millisecond, func() {
+		atomic.AddInt32(&count, 1)
+		panic("boom")
+	}, WithSupervisor(Supervisor{MaxCrashes: 2, Backoff: time.Millisecond}))
+
+	assert.Equal(t, ReasonCrashed, dt.Run())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&count))
+	assert.Equal(t, HealthFailed, dt.Health())
+}
+
+func TestDynamicTicker_SupervisorHookPanic(t *testing.T) {
+	var ticks int32
+
+	dt := NewDynamicTicker(context.Background(), 2*time.Millisecond, func() {},
+		WithMaxRuns(3),
+		WithOnTick(func(RunInfo) {
+			if atomic.AddInt32(&ticks, 1) == 1 {
+				panic("hook")
+			}
+		}),
+		WithSupervisor(Supervisor{Backoff: time.Millisecond}))
+
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&ticks))
+}
+
+func TestDynamicTicker_SupervisorKeepsInflightRuns(t *testing.T) {
+	var (
+		count, running, peak int32
+		release              = make(chan struct{})
+	)
+
+	dt := NewDynamicTicker(context.Background(), 2*time.Millisecond, func() {
+		n := atomic.AddInt32(&count, 1)
+
+		r := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for p := atomic.LoadInt32(&peak); r > p && !atomic.CompareAndSwapInt32(&peak, p, r); p = atomic.LoadInt32(&peak) {
+		}
+
+		switch n {
+		case 1:
+			<-release
+		case 2:
+			panic("boom")
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}, WithOverlapPolicy(OverlapConcurrent), WithMaxConcurrent(2), WithMaxRuns(6),
+		WithSupervisor(Supervisor{Backoff: time.Millisecond}))
+
+	done := make(chan StopReason, 1)
+	go func() { done <- dt.Run() }()
+
+	// The first run still holds one of the 2 slots after the restart.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+	close(release)
+
+	assert.Equal(t, ReasonMaxRuns, <-done)
+	assert.Equal(t, int32(6), atomic.LoadInt32(&count))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestDynamicTicker_SupervisorResumesSchedule(t *testing.T) {
+	var (
+		count   int32
+		mu      sync.Mutex
+		changes [][2]time.Duration
+	)
+
+	dt := NewDynamicTicker(context.Background(), 10*time.Millisecond, func() {
+		if atomic.AddInt32(&count, 1) == 1 {
+			panic("boom")
+		}
+	}, WithRunOnStart(), WithMaxRuns(3),
+		WithErrorBackoff(2, time.Second),
+		WithOnPeriodChange(func(old, new time.Duration) {
+			mu.Lock()
+			changes = append(changes, [2]time.Duration{old, new})
+			mu.Unlock()
+		}),
+		WithSupervisor(Supervisor{Backoff: time.Millisecond}))
+
+	start := time.Now()
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+
+	// The restart neither runs the task on start again nor resets the period
+	// and the backoff of the failure: the runs after the crash are 20ms apart.
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.True(t, len(changes) >= 2) {
+		assert.Equal(t, [2]time.Duration{0, 10 * time.Millisecond}, changes[0])
+		assert.Equal(t, [2]time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, changes[1])
+	}
+	for _, c := range changes[1:] {
+		assert.NotZero(t, c[0])
+	}
+}
+
+func TestDynamicTicker_SupervisorStopWhileRecovering(t *testing.T) {
+	dt := NewDynamicTicker(context.Background(), time.Millisecond, func() { panic("boom") },
+		WithSupervisor(Supervisor{Backoff: time.Hour}))
+
+	done := make(chan StopReason, 1)
+	go func() { done <- dt.Run() }()
+
+	assert.Eventually(t, func() bool { return dt.Health() == HealthRecovering }, time.Second, time.Millisecond)
+
+	dt.Stop()
+	assert.Equal(t, ReasonCancelled, <-done)
+}
+
+func TestDynamicTicker_UnsupervisedTaskPanic(t *testing.T) {
+	dt := NewDynamicTicker(context.Background(), time.Millisecond, func() { panic("boom") }, WithMaxRuns(2))
+
+	assert.Equal(t, ReasonMaxRuns, dt.Run())
+	assert.Equal(t, 2, dt.Stats().ConsecutiveFailures)
+}
@@ -0,0 +1,64 @@
+package dynamicticker
+
+import (
+	"context"
+	"time"
+)
+
+// Ticker is a drop-in replacement for time.Ticker whose period can change at
+// runtime. Like time.Ticker it delivers the ticks on C and drops them for
+// slow receivers, but a zero or negative period pauses it instead of
+// panicking.
+type Ticker struct {
+	C <-chan time.Time
+
+	dt *DynamicTicker
+}
+
+// NewTicker starts a Ticker with period d. Options that only matter to the
+// task, such as the overlap policy, have no effect.
+func NewTicker(d time.Duration, opts ...Option) *Ticker {
+	c := make(chan time.Time, 1)
+
+	dt := NewDynamicTicker(context.Background(), d, func() {
+		select {
+		case c <- time.Now():
+		default:
+		}
+	}, opts...)
+	go dt.Run()
+
+	return &Ticker{C: c, dt: dt}
+}
+
+// Reset is time.Ticker.Reset: it changes the period to d and counts the next
+// tick from now. It also resumes a paused ticker unless d is not positive.
+func (t *Ticker) Reset(d time.Duration) {
+	t.dt.reset(d)
+}
+
+// SetPeriod changes the period, keeping the phase when it does not change. A
+// zero or negative value pauses the ticker.
+func (t *Ticker) SetPeriod(d time.Duration) {
+	t.dt.SetPeriod(d)
+}
+
+func (t *Ticker) Period() time.Duration {
+	return t.dt.Period()
+}
+
+func (t *Ticker) Pause() {
+	t.dt.Pause()
+}
+
+// Resume restarts the ticks after Pause or after the period was set to zero,
+// see DynamicTicker.Resume.
+func (t *Ticker) Resume() {
+	t.dt.Resume()
+}
+
+// Stop turns off the ticker for good. Like time.Ticker.Stop it does not close
+// C.
+func (t *Ticker) Stop() {
+	t.dt.Stop()
+}
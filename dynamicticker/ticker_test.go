@@ -0,0 +1,86 @@
+package dynamicticker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTicker(t *testing.T) {
+	ticker := NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		<-ticker.C
+	}
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+
+	ticker.SetPeriod(0)
+	drain(ticker.C)
+	select {
+	case <-ticker.C:
+		t.Fatal("paused ticker ticked")
+	case <-time.After(40 * time.Millisecond):
+	}
+
+	ticker.Reset(5 * time.Millisecond)
+	select {
+	case <-ticker.C:
+	case <-time.After(time.Second):
+		t.Fatal("reset ticker did not tick")
+	}
+}
+
+func TestTicker_ResetRestartsPhase(t *testing.T) {
+	ticker := NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(30 * time.Millisecond)
+		ticker.Reset(50 * time.Millisecond)
+	}
+
+	select {
+	case <-ticker.C:
+		t.Fatal("ticker ticked although Reset kept postponing it")
+	default:
+	}
+}
+
+func TestTicker_PauseAndStop(t *testing.T) {
+	ticker := NewTicker(5 * time.Millisecond)
+
+	ticker.Pause()
+	time.Sleep(5 * time.Millisecond)
+	drain(ticker.C)
+	select {
+	case <-ticker.C:
+		t.Fatal("paused ticker ticked")
+	case <-time.After(30 * time.Millisecond):
+	}
+	assert.Equal(t, 5*time.Millisecond, ticker.Period())
+
+	ticker.Resume()
+	<-ticker.C
+
+	ticker.Stop()
+	time.Sleep(5 * time.Millisecond)
+	drain(ticker.C)
+	select {
+	case <-ticker.C:
+		t.Fatal("stopped ticker ticked")
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func drain(c <-chan time.Time) {
+	for {
+		select {
+		case <-c:
+		default:
+			return
+		}
+	}
+}
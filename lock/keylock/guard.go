@@ -0,0 +1,155 @@
+package keylock
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// Misuse describes a wrong use of the locks reported in strict mode, such as
+// unlocking a key held by a guard or releasing a guard whose lock was taken
+// away.
+type Misuse struct {
+	Key string
+	Msg string
+	// OwnerStack is where the guard involved took the lock.
+	OwnerStack []byte
+	// Stack is where the misuse happened.
+	Stack []byte
+
+	fatal bool
+}
+
+func (m *Misuse) Error() string {
+	return fmt.Sprintf("keylock: %s: %s", m.Msg, m.Key)
+}
+
+// WithStrictMode records where guards take their locks and reports every
+// misuse to handler, or panics with the *Misuse when handler is nil. Outside
+// of strict mode misuses are tolerated.
+func WithStrictMode(handler func(m *Misuse)) Option {
+	return func(c *options) {
+		c.strict = true
+		c.misuseHandler = handler
+	}
+}
+
+func (kl *KeyLock) reportMisuse(m *Misuse) {
+	if !kl.opts.strict {
+		return
+	}
+
+	m.Stack = debug.Stack()
+	if kl.opts.misuseHandler == nil {
+		panic(m)
+	}
+
+	kl.opts.misuseHandler(m)
+}
+
+// owner identifies a guard holding a lock.
+type owner struct {
+	token uint64
+	stack []byte
+}
+
+// Guard is a lock owned by whoever acquired it, only its Release releases it.
+type Guard struct {
+	kl    *KeyLock
+	key   string
+	write bool
+	owner *owner
+
+	once sync.Once
+}
+
+func (kl *KeyLock) newOwner() *owner {
+	o := &owner{token: atomic.AddUint64(&kl.tokens, 1)}
+	if kl.opts.strict {
+		o.stack = debug.Stack()
+	}
+
+	return o
+}
+
+func (kl *KeyLock) acquire(ctx context.Context, key string, write bool) (*Guard, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	o := kl.newOwner()
+	if err := kl.xLock(ctx, key, write, o); err != nil {
+		return nil, err
+	}
+
+	return &Guard{kl: kl, key: key, write: write, owner: o}, nil
+}
+
+// Acquire is AcquireCtx without a deadline.
+func (kl *KeyLock) Acquire(key string) (*Guard, error) {
+	return kl.acquire(context.Background(), key, true)
+}
+
+// AcquireCtx write-locks key like LockCtx and returns the guard of the lock.
+func (kl *KeyLock) AcquireCtx(ctx context.Context, key string) (*Guard, error) {
+	return kl.acquire(ctx, key, true)
+}
+
+// RAcquire is RAcquireCtx without a deadline.
+func (kl *KeyLock) RAcquire(key string) (*Guard, error) {
+	return kl.acquire(context.Background(), key, false)
+}
+
+// RAcquireCtx read-locks key like RLockCtx and returns the guard of the lock.
+func (kl *KeyLock) RAcquireCtx(ctx context.Context, key string) (*Guard, error) {
+	return kl.acquire(ctx, key, false)
+}
+
+func (g *Guard) Key() string {
+	return g.key
+}
+
+// Held reports whether the guard still holds its lock.
+func (g *Guard) Held() bool {
+	return g.kl.owns(g.key, g.owner)
+}
+
+func (kl *KeyLock) owns(key string, o *owner) bool {
+	held := false
+
+	kl.shards.RemoveCb(key, func(key string, le *lockEntry, exists bool) bool {
+		held = exists && le.owners[o.token] != nil
+		return false
+	})
+
+	return held
+}
+
+// Release releases the lock, calling it again is a no-op. Releasing a lock
+// taken away from the guard, e.g. by Unlock, is a misuse.
+func (g *Guard) Release() {
+	g.once.Do(func() {
+		if m := g.kl.releaseLock(g.key, g.write, g.owner.token); m != nil {
+			m.OwnerStack = g.owner.stack
+			g.kl.reportMisuse(m)
+		}
+	})
+}
+
+// WithLock runs fn with key write-locked, releasing it even if fn panics.
+func (kl *KeyLock) WithLock(key string, fn func()) {
+	g, _ := kl.Acquire(key)
+	defer g.Release()
+
+	fn()
+}
+
+// WithRLock runs fn with key read-locked, releasing it even if fn panics.
+func (kl *KeyLock) WithRLock(key string, fn func()) {
+	g, _ := kl.RAcquire(key)
+	defer g.Release()
+
+	fn()
+}
@@ -0,0 +1,113 @@
+package keylock
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuard(t *testing.T) {
+	kl := New()
+
+	g, err := kl.Acquire("key")
+	assert.NoError(t, err)
+	assert.True(t, g.Held())
+	assert.False(t, kl.TryRLock("key"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = kl.RAcquireCtx(ctx, "key")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	g.Release()
+	g.Release()
+	assert.False(t, g.Held())
+
+	r1, err := kl.RAcquire("key")
+	assert.NoError(t, err)
+	r2, err := kl.RAcquire("key")
+	assert.NoError(t, err)
+
+	holders, _ := kl.Status("key")
+	assert.Equal(t, 2, holders)
+
+	r1.Release()
+	r1.Release()
+	holders, _ = kl.Status("key")
+	assert.Equal(t, 1, holders)
+
+	r2.Release()
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_WithLock(t *testing.T) {
+	kl := New()
+
+	assert.Panics(t, func() {
+		kl.WithLock("key", func() { panic("boom") })
+	})
+	assert.True(t, kl.TryLock("key"))
+	kl.Unlock("key")
+
+	kl.WithRLock("key", func() {
+		assert.True(t, kl.TryRLock("key"))
+		kl.RUnlock("key")
+		assert.False(t, kl.TryLock("key"))
+	})
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_StrictMode(t *testing.T) {
+	var misuses []*Misuse
+	kl := New(WithStrictMode(func(m *Misuse) { misuses = append(misuses, m) }))
+
+	g, err := kl.Acquire("key")
+	assert.NoError(t, err)
+
+	// Someone else unlocks the key of the guard.
+	kl.Unlock("key")
+	assert.False(t, g.Held())
+	g.Release()
+
+	if assert.Len(t, misuses, 2) {
+		assert.Equal(t, "unlock of a key held by a guard", misuses[0].Msg)
+		assert.Equal(t, "release of a lock the guard no longer holds", misuses[1].Msg)
+		for _, m := range misuses {
+			assert.Equal(t, "key", m.Key)
+			assert.True(t, strings.Contains(string(m.OwnerStack), "TestKeyLock_StrictMode"))
+			assert.True(t, strings.Contains(string(m.Stack), "TestKeyLock_StrictMode"))
+		}
+	}
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+
+	strict := New(WithStrictMode(nil))
+	g, _ = strict.Acquire("key")
+	assert.Panics(t, func() { strict.Unlock("key") })
+	assert.Panics(t, g.Release)
+}
+
+func TestKeyLock_LaxMode(t *testing.T) {
+	kl := New()
+
+	g, _ := kl.Acquire("key")
+	kl.Unlock("key")
+	assert.NotPanics(t, g.Release)
+
+	// The guard does not release a lock that is not its own.
+	kl.Lock("key")
+	g.Release()
+	assert.False(t, kl.TryLock("key"))
+	kl.Unlock("key")
+}
@@ -1,32 +1,52 @@
 package keylock
 
 import (
+	"container/list"
+	"context"
 	"sync"
 	"time"
 
 	cmap "github.com/orcaman/concurrent-map/v2"
+	"github.com/pqiaohaoq/gotools/log"
+	"github.com/pqiaohaoq/gotools/timewheel"
+	"go.uber.org/zap"
 )
 
 var defaultConfig = &options{
 	MaxPollInternval:    16 * time.Millisecond,
 	InitialPollInterval: 1 * time.Millisecond,
+
+	logger: zap.NewNop().Sugar(),
 }
 
 type options struct {
 	MaxPollInternval    time.Duration
 	InitialPollInterval time.Duration
+
+	strict        bool
+	misuseHandler func(*Misuse)
+
+	leaseScheduler timewheel.Scheduler
+	metrics        *Metrics
+	logger         log.Logger
 }
 
 type Option func(*options)
 
+// Deprecated: KeyLock no longer polls, waiters are woken up on unlock.
 func WithMaxPollInterval(d time.Duration) Option {
 	return func(c *options) { c.MaxPollInternval = d }
 }
 
+// Deprecated: KeyLock no longer polls, waiters are woken up on unlock.
 func WithInitialPollInterval(d time.Duration) Option {
 	return func(c *options) { c.InitialPollInterval = d }
 }
 
+func WithLogger(l log.Logger) Option {
+	return func(c *options) { c.logger = l }
+}
+
 func applyOpts(opts []Option) options {
 	configCpy := *defaultConfig
 
@@ -37,172 +57,362 @@ func applyOpts(opts []Option) options {
 	return configCpy
 }
 
+// waiter is a goroutine queued for a key. ready is closed once the lock is
+// handed over to it.
+type waiter struct {
+	write   bool
+	owner   *owner
+	granted bool
+	ready   chan struct{}
+}
+
+// lockEntry is the state of a locked key. It is only accessed under the lock
+// of its shard, i.e. in the callbacks of the cmap.
 type lockEntry struct {
+	// holders is the number of readers, or 1 for a writer.
 	holders int
+	writer  bool
 	waiters int
 
-	mu sync.RWMutex
+	// queue holds the *waiter in FIFO order.
+	queue list.List
+	// owners are the holders that took the lock through a Guard.
+	owners map[uint64]*owner
+	// upgrader is the reader waiting in Upgrade for the other readers to
+	// leave. It goes before the queue.
+	upgrader *waiter
+
+	// timed entries record since when they are locked for the metrics.
+	timed       bool
+	lockedSince time.Time
+}
+
+// canGrant reports whether a new lock can be granted without overtaking the
+// queued waiters.
+func (le *lockEntry) canGrant(write bool) bool {
+	if le.queue.Len() > 0 || le.writer || le.upgrader != nil {
+		return false
+	}
+
+	return !write || le.holders == 0
+}
+
+func (le *lockEntry) hold(write bool, o *owner) {
+	if le.timed && le.holders == 0 {
+		le.lockedSince = time.Now()
+	}
+
+	le.holders++
+	le.writer = write
+
+	if o != nil {
+		if le.owners == nil {
+			le.owners = make(map[uint64]*owner)
+		}
+		le.owners[o.token] = o
+	}
+}
+
+// grant hands the lock over to the pending upgrader once it is the last
+// reader, otherwise to the waiters at the head of the queue: a single writer
+// or all the readers up to the next writer.
+func (le *lockEntry) grant() {
+	if w := le.upgrader; w != nil {
+		if le.holders == 1 {
+			le.upgrader = nil
+			le.writer = true
+
+			w.granted = true
+			close(w.ready)
+		}
+
+		return
+	}
+
+	for e := le.queue.Front(); e != nil; e = le.queue.Front() {
+		w := e.Value.(*waiter)
+		if le.writer || w.write && le.holders > 0 {
+			return
+		}
+
+		le.queue.Remove(e)
+		le.waiters--
+		le.hold(w.write, w.owner)
+
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (le *lockEntry) idle() bool {
+	return le.holders == 0 && le.waiters == 0
 }
 
 type KeyLock struct {
+	tokens uint64 // first for 64-bit atomic alignment
+
 	shards cmap.ConcurrentMap[string, *lockEntry]
 	opts   options
 }
 
-func (kl *KeyLock) prepareLock(key string) *lockEntry {
-	return kl.shards.Upsert(key, &lockEntry{}, func(exist bool, valueInMap, newValue *lockEntry) *lockEntry {
+// prepareLock takes the lock of key when it is free, and queues a waiter
+// otherwise unless wait is false. It returns the queued waiter, if any, and
+// whether the lock was taken. o is the guard taking the lock, nil for the
+// plain Lock and RLock.
+func (kl *KeyLock) prepareLock(key string, write, wait bool, o *owner) (w *waiter, acquired bool) {
+	kl.shards.Upsert(key, &lockEntry{timed: kl.opts.metrics != nil}, func(exist bool, valueInMap, newValue *lockEntry) *lockEntry {
 		le := newValue
 		if exist {
 			le = valueInMap
 		}
 
-		le.waiters++
+		switch {
+		case le.canGrant(write):
+			le.hold(write, o)
+			acquired = true
+		case wait:
+			w = &waiter{write: write, owner: o, ready: make(chan struct{})}
+			le.queue.PushBack(w)
+			le.waiters++
+		}
 
 		return le
 	})
+
+	if acquired {
+		kl.opts.metrics.acquired(key, 0, false)
+	}
+
+	return w, acquired
 }
 
-func (kl *KeyLock) commitLock(key string, le *lockEntry) {
-	kl.shards.Upsert(key, le, func(exist bool, valueInMap, newValue *lockEntry) *lockEntry {
-		lock := newValue
-		if exist {
-			lock = valueInMap
+// cancelLock dequeues w. It reports true when w was granted the lock in the
+// meantime, which the caller then holds.
+func (kl *KeyLock) cancelLock(key string, w *waiter) bool {
+	cancelled := false
+
+	kl.shards.RemoveCb(key, func(key string, le *lockEntry, exists bool) bool {
+		if !exists || w.granted {
+			return false
 		}
 
-		lock.waiters--
-		lock.holders++
+		for e := le.queue.Front(); e != nil; e = e.Next() {
+			if e.Value.(*waiter) == w {
+				le.queue.Remove(e)
+				le.waiters--
+				break
+			}
+		}
+		cancelled = true
 
-		return lock
+		// The waiters queued behind w may be able to go now.
+		le.grant()
+
+		return le.idle()
 	})
-}
 
-func (kl *KeyLock) releaseLock(key string, unlockFunc func(*sync.RWMutex)) {
-	var le *lockEntry = nil
+	return !cancelled
+}
 
-	kl.shards.RemoveCb(key, func(key string, v *lockEntry, exists bool) bool {
-		if !exists {
+// releaseLock releases a lock of key, the one of the guard token unless token
+// is 0. A guard releases its lock in the mode it holds it now, which Upgrade
+// and Downgrade may have changed, so write only matters for a 0 token. It
+// reports the misuse it detected, if any, with the lock left as is for the
+// fatal ones.
+func (kl *KeyLock) releaseLock(key string, write bool, token uint64) *Misuse {
+	var (
+		misuse *Misuse
+		held   time.Duration
+	)
+
+	kl.shards.RemoveCb(key, func(key string, le *lockEntry, exists bool) bool {
+		switch {
+		case token != 0 && (!exists || le.owners[token] == nil):
+			misuse = &Misuse{Key: key, Msg: "release of a lock the guard no longer holds"}
+			return false
+		case !exists || le.holders == 0:
+			misuse = &Misuse{Key: key, Msg: "unlock of unlocked key", fatal: true}
+			return false
+		case token != 0:
+		case write && !le.writer:
+			misuse = &Misuse{Key: key, Msg: "Unlock of read-locked key", fatal: true}
 			return false
+		case !write && le.writer:
+			misuse = &Misuse{Key: key, Msg: "RUnlock of write-locked key", fatal: true}
+			return false
+		}
+
+		if token != 0 {
+			delete(le.owners, token)
+		} else if len(le.owners) == le.holders {
+			// Every holder is a guard, so the caller releases the lock of one
+			// of them.
+			for t, o := range le.owners {
+				misuse = &Misuse{Key: key, Msg: "unlock of a key held by a guard", OwnerStack: o.stack}
+				delete(le.owners, t)
+				break
+			}
 		}
 
-		le = v
 		le.holders--
+		if le.holders == 0 {
+			le.writer = false
+			if le.timed {
+				held = time.Since(le.lockedSince)
+			}
+		}
+		le.grant()
 
-		return le.holders == 0 && le.waiters == 0
+		return le.idle()
 	})
 
-	if le == nil {
-		panic("keylock: unlock of unlocked key")
+	if held > 0 {
+		kl.opts.metrics.released(held)
 	}
 
-	unlockFunc(&le.mu)
+	return misuse
 }
 
-func (kl *KeyLock) cancelLock(key string) {
-	kl.shards.RemoveCb(key, func(key string, v *lockEntry, exists bool) bool {
-		if !exists {
-			return false
+// unlock is releaseLock for Unlock and RUnlock.
+func (kl *KeyLock) unlock(key string, write bool) {
+	misuse := kl.releaseLock(key, write, 0)
+	if misuse == nil {
+		return
+	}
+
+	// Panic outside of the callback, which runs with the shard locked.
+	if misuse.fatal {
+		panic("keylock: " + misuse.Msg)
+	}
+
+	kl.reportMisuse(misuse)
+}
+
+// xLock takes the lock of key for o, waiting in line until ctx is done.
+func (kl *KeyLock) xLock(ctx context.Context, key string, write bool, o *owner) error {
+	w, acquired := kl.prepareLock(key, write, true, o)
+	if acquired {
+		return nil
+	}
+
+	start := time.Now()
+
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		if !kl.cancelLock(key, w) {
+			kl.opts.metrics.timedOut(key, time.Since(start))
+			return ctx.Err()
 		}
+	}
 
-		v.waiters--
+	kl.opts.metrics.acquired(key, time.Since(start), true)
 
-		return v.waiters == 0 && v.holders == 0
-	})
+	return nil
 }
 
 func (kl *KeyLock) Lock(key string) {
-	le := kl.prepareLock(key)
-	le.mu.Lock()
-	kl.commitLock(key, le)
+	_ = kl.xLock(context.Background(), key, true, nil)
 }
 
 func (kl *KeyLock) RLock(key string) {
-	le := kl.prepareLock(key)
-	le.mu.RLock()
-	kl.commitLock(key, le)
+	_ = kl.xLock(context.Background(), key, false, nil)
 }
 
 func (kl *KeyLock) RUnlock(key string) {
-	kl.releaseLock(key, (*sync.RWMutex).RUnlock)
+	kl.unlock(key, false)
 }
 
-func (kl *KeyLock) tryXLock(key string, tryXLockFunc func(*sync.RWMutex) bool) bool {
-	le := kl.prepareLock(key)
-	if tryXLockFunc(&le.mu) {
-		kl.commitLock(key, le)
-		return true
-	}
-
-	kl.cancelLock(key)
-
-	return false
+func (kl *KeyLock) tryXLock(key string, write bool) bool {
+	_, acquired := kl.prepareLock(key, write, false, nil)
+	return acquired
 }
 
 func (kl *KeyLock) TryLock(key string) bool {
-	return kl.tryXLock(key, (*sync.RWMutex).TryLock)
+	return kl.tryXLock(key, true)
 }
 
 func (kl *KeyLock) TryRLock(key string) bool {
-	return kl.tryXLock(key, (*sync.RWMutex).TryRLock)
+	return kl.tryXLock(key, false)
 }
 
 func (kl *KeyLock) TryLockWithTimeout(key string, timeout time.Duration) bool {
-	return kl.TryXLockWithTimeout(key, timeout, (*sync.RWMutex).TryLock)
+	return kl.xLockWithTimeout(key, timeout, true)
 }
 
 func (kl *KeyLock) TryRlockWithTimeout(key string, timeout time.Duration) bool {
-	return kl.TryXLockWithTimeout(key, timeout, (*sync.RWMutex).TryRLock)
+	return kl.xLockWithTimeout(key, timeout, false)
 }
 
+// Deprecated: use TryLockWithTimeout or TryRlockWithTimeout. tryXLockFunc
+// only tells whether to take a write or a read lock.
 func (kl *KeyLock) TryXLockWithTimeout(key string, timeout time.Duration, tryXLockFunc func(*sync.RWMutex) bool) bool {
-	le := kl.prepareLock(key)
-	if tryXLockFunc(&le.mu) {
-		kl.commitLock(key, le)
+	// Only TryRLock succeeds on a read-locked mutex.
+	var probe sync.RWMutex
+	probe.RLock()
+	write := !tryXLockFunc(&probe)
+
+	return kl.xLockWithTimeout(key, timeout, write)
+}
+
+func (kl *KeyLock) xLockWithTimeout(key string, timeout time.Duration, write bool) bool {
+	w, acquired := kl.prepareLock(key, write, true, nil)
+	if acquired {
 		return true
 	}
 
-	acquired := false
-	defer func() {
-		if !acquired {
-			kl.cancelLock(key)
-		}
-	}()
+	start := time.Now()
 
 	timer := time.NewTimer(timeout)
 	defer timer.Stop()
 
-	pollInterval := kl.opts.InitialPollInterval
-	maxPollInterval := kl.opts.MaxPollInternval
+	select {
+	case <-w.ready:
+	case <-timer.C:
+		if !kl.cancelLock(key, w) {
+			kl.opts.metrics.timedOut(key, time.Since(start))
+			return false
+		}
+	}
 
-	for {
-		select {
-		case <-timer.C:
-		default:
-			time.Sleep(pollInterval)
-			if tryXLockFunc(&le.mu) {
-				kl.commitLock(key, le)
-				acquired = true
+	kl.opts.metrics.acquired(key, time.Since(start), true)
 
-				return true
-			}
+	return true
+}
 
-			pollInterval *= 2
-			if pollInterval >= maxPollInterval {
-				pollInterval = maxPollInterval
-			}
-		}
+// LockCtx is Lock giving up with ctx.Err() when ctx is done first.
+func (kl *KeyLock) LockCtx(ctx context.Context, key string) error {
+	return kl.xLockCtx(ctx, key, true)
+}
+
+// RLockCtx is RLock giving up with ctx.Err() when ctx is done first.
+func (kl *KeyLock) RLockCtx(ctx context.Context, key string) error {
+	return kl.xLockCtx(ctx, key, false)
+}
+
+func (kl *KeyLock) xLockCtx(ctx context.Context, key string, write bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+
+	return kl.xLock(ctx, key, write, nil)
 }
 
 func (kl *KeyLock) Unlock(key string) {
-	kl.releaseLock(key, (*sync.RWMutex).Unlock)
+	kl.unlock(key, true)
 }
 
 func (kl *KeyLock) Status(key string) (holders int, waiters int) {
-	if le, ok := kl.shards.Get(key); ok {
-		return le.holders, le.waiters
-	}
+	// RemoveCb never removing anything reads the entry under the shard lock.
+	kl.shards.RemoveCb(key, func(key string, le *lockEntry, exists bool) bool {
+		if exists {
+			holders, waiters = le.holders, le.waiters
+		}
+
+		return false
+	})
 
-	return 0, 0
+	return holders, waiters
 }
 
 func New(opts ...Option) *KeyLock {
@@ -213,6 +423,13 @@ func New(opts ...Option) *KeyLock {
 		opts: options{
 			MaxPollInternval:    o.MaxPollInternval,
 			InitialPollInterval: o.InitialPollInterval,
+
+			strict:        o.strict,
+			misuseHandler: o.misuseHandler,
+
+			leaseScheduler: o.leaseScheduler,
+			metrics:        o.metrics,
+			logger:         o.logger,
 		},
 	}
 }
@@ -0,0 +1,194 @@
+package keylock
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map/v2"
+)
+
+// pollingKeyLock is the former KeyLock, which polls for the lock with an
+// exponential backoff, kept as a baseline for the benchmarks.
+type pollingKeyLock struct {
+	shards cmap.ConcurrentMap[string, *pollingLockEntry]
+	opts   options
+}
+
+type pollingLockEntry struct {
+	holders int
+	waiters int
+
+	mu sync.RWMutex
+}
+
+func newPollingKeyLock() *pollingKeyLock {
+	return &pollingKeyLock{shards: cmap.New[*pollingLockEntry](), opts: *defaultConfig}
+}
+
+func (kl *pollingKeyLock) prepareLock(key string) *pollingLockEntry {
+	return kl.shards.Upsert(key, &pollingLockEntry{}, func(exist bool, valueInMap, newValue *pollingLockEntry) *pollingLockEntry {
+		le := newValue
+		if exist {
+			le = valueInMap
+		}
+
+		le.waiters++
+
+		return le
+	})
+}
+
+func (kl *pollingKeyLock) commitLock(key string, le *pollingLockEntry) {
+	kl.shards.Upsert(key, le, func(exist bool, valueInMap, newValue *pollingLockEntry) *pollingLockEntry {
+		lock := newValue
+		if exist {
+			lock = valueInMap
+		}
+
+		lock.waiters--
+		lock.holders++
+
+		return lock
+	})
+}
+
+func (kl *pollingKeyLock) cancelLock(key string) {
+	kl.shards.RemoveCb(key, func(key string, v *pollingLockEntry, exists bool) bool {
+		if !exists {
+			return false
+		}
+
+		v.waiters--
+
+		return v.waiters == 0 && v.holders == 0
+	})
+}
+
+func (kl *pollingKeyLock) Lock(key string) {
+	le := kl.prepareLock(key)
+	le.mu.Lock()
+	kl.commitLock(key, le)
+}
+
+func (kl *pollingKeyLock) Unlock(key string) {
+	var le *pollingLockEntry
+
+	kl.shards.RemoveCb(key, func(key string, v *pollingLockEntry, exists bool) bool {
+		le = v
+		le.holders--
+
+		return le.holders == 0 && le.waiters == 0
+	})
+
+	le.mu.Unlock()
+}
+
+func (kl *pollingKeyLock) TryLockWithTimeout(key string, timeout time.Duration) bool {
+	le := kl.prepareLock(key)
+	if le.mu.TryLock() {
+		kl.commitLock(key, le)
+		return true
+	}
+
+	acquired := false
+	defer func() {
+		if !acquired {
+			kl.cancelLock(key)
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	pollInterval := kl.opts.InitialPollInterval
+	maxPollInterval := kl.opts.MaxPollInternval
+
+	for {
+		select {
+		case <-timer.C:
+		default:
+			time.Sleep(pollInterval)
+			if le.mu.TryLock() {
+				kl.commitLock(key, le)
+				acquired = true
+
+				return true
+			}
+
+			pollInterval *= 2
+			if pollInterval >= maxPollInterval {
+				pollInterval = maxPollInterval
+			}
+		}
+	}
+}
+
+type benchLocker interface {
+	Lock(key string)
+	Unlock(key string)
+	TryLockWithTimeout(key string, timeout time.Duration) bool
+}
+
+func benchLockers() []struct {
+	name string
+	new  func() benchLocker
+} {
+	return []struct {
+		name string
+		new  func() benchLocker
+	}{
+		{"queue", func() benchLocker { return New() }},
+		{"polling", func() benchLocker { return newPollingKeyLock() }},
+	}
+}
+
+func BenchmarkKeyLock_Uncontended(b *testing.B) {
+	for _, l := range benchLockers() {
+		b.Run(l.name, func(b *testing.B) {
+			kl := l.new()
+
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					key := strconv.Itoa(i)
+					kl.Lock(key)
+					kl.Unlock(key)
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkKeyLock_Contended(b *testing.B) {
+	for _, l := range benchLockers() {
+		b.Run(l.name, func(b *testing.B) {
+			kl := l.new()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					kl.Lock("key")
+					kl.Unlock("key")
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkKeyLock_ContendedWithTimeout(b *testing.B) {
+	for _, l := range benchLockers() {
+		b.Run(l.name, func(b *testing.B) {
+			kl := l.new()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if kl.TryLockWithTimeout("key", time.Second) {
+						kl.Unlock("key")
+					}
+				}
+			})
+		})
+	}
+}
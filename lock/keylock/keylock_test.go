@@ -1,8 +1,10 @@
 package keylock
 
 import (
+	"context"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -34,3 +36,210 @@ func Test_ConcurrentLockUnlock(t *testing.T) {
 	assert.Equal(t, 0, holders)
 	assert.Equal(t, 0, waiters)
 }
+
+func TestKeyLock_LockCtx(t *testing.T) {
+	kl := New()
+	key := "test_key"
+
+	assert.NoError(t, kl.LockCtx(context.Background(), key))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, kl.LockCtx(ctx, key), context.DeadlineExceeded)
+	assert.ErrorIs(t, kl.RLockCtx(ctx, key), context.DeadlineExceeded)
+
+	holders, waiters := kl.Status(key)
+	assert.Equal(t, 1, holders)
+	assert.Equal(t, 0, waiters)
+
+	done := make(chan error, 1)
+	go func() { done <- kl.RLockCtx(context.Background(), key) }()
+
+	time.Sleep(5 * time.Millisecond)
+	kl.Unlock(key)
+	assert.NoError(t, <-done)
+
+	assert.NoError(t, kl.RLockCtx(context.Background(), key))
+	holders, _ = kl.Status(key)
+	assert.Equal(t, 2, holders)
+
+	kl.RUnlock(key)
+	kl.RUnlock(key)
+
+	holders, waiters = kl.Status(key)
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_LockCtxCancelled(t *testing.T) {
+	kl := New()
+	key := "test_key"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, kl.LockCtx(ctx, key), context.Canceled)
+
+	holders, waiters := kl.Status(key)
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_TryLockWithTimeout(t *testing.T) {
+	kl := New()
+	key := "test_key"
+
+	assert.True(t, kl.TryLockWithTimeout(key, time.Millisecond))
+
+	start := time.Now()
+	assert.False(t, kl.TryLockWithTimeout(key, 20*time.Millisecond))
+	assert.False(t, kl.TryRlockWithTimeout(key, 20*time.Millisecond))
+	assert.False(t, kl.TryXLockWithTimeout(key, 20*time.Millisecond, (*sync.RWMutex).TryLock))
+	assert.True(t, time.Since(start) < time.Second)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		kl.Unlock(key)
+	}()
+	assert.True(t, kl.TryXLockWithTimeout(key, time.Second, (*sync.RWMutex).TryRLock))
+	assert.True(t, kl.TryRLock(key))
+	assert.False(t, kl.TryLock(key))
+
+	kl.RUnlock(key)
+	kl.RUnlock(key)
+
+	holders, waiters := kl.Status(key)
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_FIFO(t *testing.T) {
+	kl := New()
+	key := "test_key"
+
+	kl.Lock(key)
+
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			kl.Lock(key)
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			kl.Unlock(key)
+		}(i)
+
+		assert.Eventually(t, func() bool {
+			_, waiters := kl.Status(key)
+			return waiters == i+1
+		}, time.Second, time.Millisecond)
+	}
+
+	kl.Unlock(key)
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, order)
+}
+
+func TestKeyLock_WriterBlocksLaterReaders(t *testing.T) {
+	kl := New()
+	key := "test_key"
+
+	kl.RLock(key)
+
+	locked := make(chan struct{})
+	go func() {
+		kl.Lock(key)
+		close(locked)
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, waiters := kl.Status(key)
+		return waiters == 1
+	}, time.Second, time.Millisecond)
+
+	// A reader may not overtake the queued writer.
+	assert.False(t, kl.TryRLock(key))
+
+	rlocked := make(chan struct{})
+	go func() {
+		kl.RLock(key)
+		close(rlocked)
+	}()
+
+	kl.RUnlock(key)
+	<-locked
+
+	select {
+	case <-rlocked:
+		t.Fatal("the reader overtook the writer")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	kl.Unlock(key)
+	<-rlocked
+	kl.RUnlock(key)
+
+	holders, waiters := kl.Status(key)
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_CancelUnblocksQueue(t *testing.T) {
+	kl := New()
+	key := "test_key"
+
+	kl.RLock(key)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- kl.LockCtx(ctx, key) }()
+
+	assert.Eventually(t, func() bool {
+		_, waiters := kl.Status(key)
+		return waiters == 1
+	}, time.Second, time.Millisecond)
+
+	rlocked := make(chan struct{})
+	go func() {
+		kl.RLock(key)
+		close(rlocked)
+	}()
+
+	// Cancelling the writer lets the reader queued behind it share the lock.
+	cancel()
+	assert.ErrorIs(t, <-errc, context.Canceled)
+	<-rlocked
+
+	kl.RUnlock(key)
+	kl.RUnlock(key)
+
+	holders, waiters := kl.Status(key)
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_Misuse(t *testing.T) {
+	kl := New()
+
+	assert.PanicsWithValue(t, "keylock: unlock of unlocked key", func() { kl.Unlock("key") })
+
+	kl.RLock("key")
+	assert.PanicsWithValue(t, "keylock: Unlock of read-locked key", func() { kl.Unlock("key") })
+	kl.RUnlock("key")
+
+	kl.Lock("key")
+	assert.PanicsWithValue(t, "keylock: RUnlock of write-locked key", func() { kl.RUnlock("key") })
+	kl.Unlock("key")
+
+	assert.True(t, kl.TryLock("key"))
+	kl.Unlock("key")
+}
@@ -0,0 +1,198 @@
+package keylock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pqiaohaoq/gotools/timewheel"
+)
+
+var (
+	ErrLeaseExpired          = errors.New("keylock: lease expired")
+	ErrLeaseTTLIsNotPositive = errors.New("keylock: lease ttl is not positive")
+)
+
+var (
+	_leaseScheduler     timewheel.Scheduler
+	_leaseSchedulerOnce sync.Once
+
+	_leaseIDs uint64
+)
+
+// WithLeaseScheduler expires the leases with s, which must be started, instead
+// of the heap scheduler shared by all the KeyLocks.
+func WithLeaseScheduler(s timewheel.Scheduler) Option {
+	return func(c *options) { c.leaseScheduler = s }
+}
+
+func (kl *KeyLock) leaseScheduler() timewheel.Scheduler {
+	if kl.opts.leaseScheduler != nil {
+		return kl.opts.leaseScheduler
+	}
+
+	_leaseSchedulerOnce.Do(func() {
+		s := timewheel.NewHeapScheduler()
+		s.Start()
+		_leaseScheduler = s
+	})
+
+	return _leaseScheduler
+}
+
+// Lease is a write lock released by force once its ttl elapses without being
+// renewed, so that a hung or leaked holder cannot keep the key locked.
+type Lease struct {
+	kl    *KeyLock
+	key   string
+	owner *owner
+
+	taskKey string
+	// holder is where the lease was taken, for the expiry log.
+	holder     string
+	acquiredAt time.Time
+
+	mu       sync.Mutex
+	released bool
+	// gen counts the renewals, an expiry task only fires for the generation
+	// it was scheduled for.
+	gen uint64
+}
+
+// LockWithLease is LockWithLeaseCtx without a deadline.
+func (kl *KeyLock) LockWithLease(key string, ttl time.Duration) (*Lease, error) {
+	return kl.lockWithLease(context.Background(), key, ttl)
+}
+
+// LockWithLeaseCtx write-locks key like LockCtx for ttl, see Lease.
+func (kl *KeyLock) LockWithLeaseCtx(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	return kl.lockWithLease(ctx, key, ttl)
+}
+
+func (kl *KeyLock) lockWithLease(ctx context.Context, key string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		return nil, ErrLeaseTTLIsNotPositive
+	}
+
+	holder := "unknown"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		holder = fmt.Sprintf("%s:%d", file, line)
+	}
+
+	g, err := kl.acquire(ctx, key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Lease{
+		kl:    kl,
+		key:   key,
+		owner: g.owner,
+
+		taskKey:    "keylock-lease-" + strconv.FormatUint(atomic.AddUint64(&_leaseIDs, 1), 10),
+		holder:     holder,
+		acquiredAt: time.Now(),
+	}
+
+	if err := kl.leaseScheduler().AddTask(ttl, l.taskKey, l.expirer(0)); err != nil {
+		g.Release()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Lease) Key() string {
+	return l.key
+}
+
+// Held reports whether the lease still holds its lock.
+func (l *Lease) Held() bool {
+	return l.kl.owns(l.key, l.owner)
+}
+
+// Renew extends the lease to ttl from now. It fails with ErrLeaseExpired once
+// the lock was released.
+func (l *Lease) Renew(ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrLeaseTTLIsNotPositive
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.released || !l.Held() {
+		return ErrLeaseExpired
+	}
+
+	// The task of the previous ttl may already be running, the new generation
+	// stops it from releasing the lock.
+	l.gen++
+
+	s := l.kl.leaseScheduler()
+	s.RemoveTask(l.taskKey)
+	if err := s.AddTask(ttl, l.taskKey, l.expirer(l.gen)); err != nil {
+		return err
+	}
+
+	// The lease may have expired between the check and the new task.
+	if !l.Held() {
+		s.RemoveTask(l.taskKey)
+		return ErrLeaseExpired
+	}
+
+	return nil
+}
+
+// Release releases the lock, or returns ErrLeaseExpired when it was already
+// released by force. Calling it again is a no-op.
+func (l *Lease) Release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.released {
+		return nil
+	}
+	l.released = true
+
+	l.kl.leaseScheduler().RemoveTask(l.taskKey)
+
+	if l.kl.releaseLock(l.key, true, l.owner.token) != nil {
+		return ErrLeaseExpired
+	}
+
+	return nil
+}
+
+func (l *Lease) expirer(gen uint64) func() {
+	return func() { l.expire(gen) }
+}
+
+func (l *Lease) expire(gen uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.released || gen != l.gen {
+		return
+	}
+
+	if l.kl.releaseLock(l.key, true, l.owner.token) != nil {
+		return
+	}
+
+	l.kl.opts.logger.Warnf("[keylock] the lease of the key %s taken at %s by %s expired, release it by force%s",
+		l.key, l.acquiredAt.Format(time.RFC3339Nano), l.holder, formatStack(l.owner.stack))
+}
+
+func formatStack(stack []byte) string {
+	if len(stack) == 0 {
+		return ""
+	}
+
+	return "\n" + string(stack)
+}
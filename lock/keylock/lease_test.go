@@ -0,0 +1,105 @@
+package keylock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pqiaohaoq/gotools/timewheel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLease_Expire(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	kl := New(WithLogger(zap.New(core).Sugar()))
+
+	_, err := kl.LockWithLease("key", 0)
+	assert.ErrorIs(t, err, ErrLeaseTTLIsNotPositive)
+
+	l, err := kl.LockWithLease("key", 20*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, l.Held())
+	assert.False(t, kl.TryLock("key"))
+
+	// The key is released once the lease expires.
+	assert.True(t, kl.TryLockWithTimeout("key", time.Second))
+	assert.False(t, l.Held())
+	assert.ErrorIs(t, l.Renew(time.Second), ErrLeaseExpired)
+	assert.ErrorIs(t, l.Release(), ErrLeaseExpired)
+	assert.NoError(t, l.Release())
+	kl.Unlock("key")
+
+	entries := logs.TakeAll()
+	if assert.Len(t, entries, 1) {
+		assert.Contains(t, entries[0].Message, "the lease of the key key")
+		assert.Contains(t, entries[0].Message, "lease_test.go")
+	}
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestLease_RenewAndRelease(t *testing.T) {
+	s := timewheel.NewHeapScheduler()
+	s.Start()
+	defer s.Stop()
+
+	kl := New(WithLeaseScheduler(s))
+
+	const ttl = 200 * time.Millisecond
+
+	l, err := kl.LockWithLease("key", ttl)
+	assert.NoError(t, err)
+
+	// The renewals keep the lease for longer than its ttl, each one well
+	// within it even when the sleeps overshoot.
+	for i := 0; i < 15; i++ {
+		time.Sleep(20 * time.Millisecond)
+		assert.NoError(t, l.Renew(ttl))
+	}
+	assert.True(t, l.Held())
+
+	assert.NoError(t, l.Release())
+	assert.False(t, l.Held())
+	assert.True(t, kl.TryLock("key"))
+
+	// The lease timer is gone with the release.
+	time.Sleep(ttl + 50*time.Millisecond)
+	holders, _ := kl.Status("key")
+	assert.Equal(t, 1, holders)
+	kl.Unlock("key")
+}
+
+// stuckScheduler never runs its tasks nor removes them, like a scheduler that
+// already popped them to run.
+type stuckScheduler struct {
+	timewheel.Scheduler
+
+	tasks []timewheel.TaskFunc
+}
+
+func (s *stuckScheduler) AddTask(delay time.Duration, key string, taskFunc timewheel.TaskFunc) error {
+	s.tasks = append(s.tasks, taskFunc)
+	return nil
+}
+
+func (s *stuckScheduler) RemoveTask(key string) {}
+
+func TestLease_RenewWhileExpiring(t *testing.T) {
+	s := &stuckScheduler{}
+	kl := New(WithLeaseScheduler(s))
+
+	l, err := kl.LockWithLease("key", time.Second)
+	assert.NoError(t, err)
+	assert.NoError(t, l.Renew(time.Second))
+
+	// The task of the first ttl fires after the renewal.
+	s.tasks[0]()
+	assert.True(t, l.Held())
+
+	s.tasks[1]()
+	assert.False(t, l.Held())
+	assert.ErrorIs(t, l.Release(), ErrLeaseExpired)
+}
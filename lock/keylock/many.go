@@ -0,0 +1,82 @@
+package keylock
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+type keyReq struct {
+	key   string
+	write bool
+}
+
+// sortKeys orders the keys and drops the duplicates, a write lock winning over
+// a read lock of the same key. Locking keys in this global order cannot
+// deadlock with another multi-key lock.
+func sortKeys(write, read []string) []keyReq {
+	modes := make(map[string]bool, len(write)+len(read))
+	for _, key := range read {
+		modes[key] = false
+	}
+	for _, key := range write {
+		modes[key] = true
+	}
+
+	reqs := make([]keyReq, 0, len(modes))
+	for key, w := range modes {
+		reqs = append(reqs, keyReq{key: key, write: w})
+	}
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].key < reqs[j].key })
+
+	return reqs
+}
+
+func (kl *KeyLock) lockMany(ctx context.Context, reqs []keyReq) (func(), error) {
+	for i, req := range reqs {
+		if err := kl.xLock(ctx, req.key, req.write, nil); err != nil {
+			kl.unlockMany(reqs[:i])
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+
+	return func() { once.Do(func() { kl.unlockMany(reqs) }) }, nil
+}
+
+func (kl *KeyLock) unlockMany(reqs []keyReq) {
+	for i := len(reqs) - 1; i >= 0; i-- {
+		kl.unlock(reqs[i].key, reqs[i].write)
+	}
+}
+
+// LockMany write-locks all the keys without risking a deadlock with other
+// multi-key locks, whatever the order of keys. The returned func unlocks them
+// all, calling it again is a no-op.
+func (kl *KeyLock) LockMany(keys ...string) (unlock func()) {
+	unlock, _ = kl.lockMany(context.Background(), sortKeys(keys, nil))
+	return unlock
+}
+
+// RLockMany is LockMany taking read locks.
+func (kl *KeyLock) RLockMany(keys ...string) (unlock func()) {
+	unlock, _ = kl.lockMany(context.Background(), sortKeys(nil, keys))
+	return unlock
+}
+
+// TryLockMany is LockMany giving up with ctx.Err() when ctx is done first, in
+// which case none of the keys is held.
+func (kl *KeyLock) TryLockMany(ctx context.Context, keys ...string) (unlock func(), err error) {
+	return kl.LockSet(ctx, keys, nil)
+}
+
+// LockSet write-locks the write keys and read-locks the read keys all at once
+// like TryLockMany. A key in both sets is write-locked.
+func (kl *KeyLock) LockSet(ctx context.Context, write, read []string) (unlock func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return kl.lockMany(ctx, sortKeys(write, read))
+}
@@ -0,0 +1,94 @@
+package keylock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyLock_LockManyNoDeadlock(t *testing.T) {
+	kl := New()
+	keys := []string{"a", "b", "c"}
+	balances := map[string]int{"a": 100, "b": 100, "c": 100}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 300; i++ {
+		from, to := keys[i%3], keys[(i+1)%3]
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock := kl.LockMany(to, from, to)
+			defer unlock()
+
+			balances[from]--
+			balances[to]++
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 300, balances["a"]+balances["b"]+balances["c"])
+	for _, key := range keys {
+		holders, waiters := kl.Status(key)
+		assert.Equal(t, 0, holders)
+		assert.Equal(t, 0, waiters)
+	}
+}
+
+func TestKeyLock_TryLockManyAllOrNothing(t *testing.T) {
+	kl := New()
+	kl.Lock("b")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	unlock, err := kl.TryLockMany(ctx, "c", "a", "b")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, unlock)
+
+	for _, key := range []string{"a", "c"} {
+		holders, waiters := kl.Status(key)
+		assert.Equal(t, 0, holders, key)
+		assert.Equal(t, 0, waiters, key)
+	}
+
+	kl.Unlock("b")
+
+	unlock, err = kl.TryLockMany(context.Background(), "c", "a", "b")
+	assert.NoError(t, err)
+	assert.False(t, kl.TryRLock("a"))
+
+	unlock()
+	unlock()
+	assert.True(t, kl.TryLock("a"))
+	kl.Unlock("a")
+}
+
+func TestKeyLock_RLockManyAndLockSet(t *testing.T) {
+	kl := New()
+
+	unlockRead := kl.RLockMany("a", "b", "a")
+	holders, _ := kl.Status("a")
+	assert.Equal(t, 1, holders)
+	assert.True(t, kl.TryRLock("b"))
+	kl.RUnlock("b")
+
+	unlock, err := kl.LockSet(context.Background(), []string{"c"}, []string{"a", "b", "c"})
+	assert.NoError(t, err)
+	assert.False(t, kl.TryRLock("c"))
+	holders, _ = kl.Status("a")
+	assert.Equal(t, 2, holders)
+
+	unlock()
+	unlockRead()
+
+	for _, key := range []string{"a", "b", "c"} {
+		holders, waiters := kl.Status(key)
+		assert.Equal(t, 0, holders, key)
+		assert.Equal(t, 0, waiters, key)
+	}
+}
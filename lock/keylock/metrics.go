@@ -0,0 +1,380 @@
+package keylock
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var defaultBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// WithMetrics records the activity of the KeyLock into m, which several
+// KeyLocks may share.
+func WithMetrics(m *Metrics) Option {
+	return func(c *options) { c.metrics = m }
+}
+
+type MetricsOption func(*metricsOptions)
+
+type metricsOptions struct {
+	buckets []time.Duration
+	window  time.Duration
+	slots   int
+	topK    int
+}
+
+// WithBuckets sets the upper bounds of the wait and hold time histograms.
+func WithBuckets(bounds ...time.Duration) MetricsOption {
+	return func(o *metricsOptions) { o.buckets = bounds }
+}
+
+// WithHotKeyWindow sets the sliding window the hot keys are ranked over, one
+// minute by default, moving by steps of window/slots.
+func WithHotKeyWindow(window time.Duration, slots int) MetricsOption {
+	return func(o *metricsOptions) {
+		o.window = window
+		o.slots = slots
+	}
+}
+
+// WithTopK sets the number of hot keys exported by WritePrometheus, 10 by
+// default.
+func WithTopK(k int) MetricsOption {
+	return func(o *metricsOptions) { o.topK = k }
+}
+
+// Metrics aggregates the lock acquisitions of a KeyLock. Only the contended
+// acquisitions and the timeouts count towards the hot keys, so that their
+// bookkeeping stays proportional to the keys that actually serialize work.
+type Metrics struct {
+	// first for 64-bit atomic alignment
+	acquisitions uint64
+	contended    uint64
+	timeouts     uint64
+
+	waitTime *histogram
+	holdTime *histogram
+
+	hot  *hotKeys
+	topK int
+}
+
+func NewMetrics(opts ...MetricsOption) *Metrics {
+	o := metricsOptions{
+		buckets: defaultBuckets,
+		window:  time.Minute,
+		slots:   6,
+		topK:    10,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.slots <= 0 {
+		o.slots = 1
+	}
+
+	return &Metrics{
+		waitTime: newHistogram(o.buckets),
+		holdTime: newHistogram(o.buckets),
+		hot:      newHotKeys(o.window, o.slots),
+		topK:     o.topK,
+	}
+}
+
+// acquired records an acquisition after waiting for wait. Like the other
+// recorders it is a no-op on a nil *Metrics.
+func (m *Metrics) acquired(key string, wait time.Duration, contended bool) {
+	if m == nil {
+		return
+	}
+
+	atomic.AddUint64(&m.acquisitions, 1)
+	m.waitTime.observe(wait)
+
+	if contended {
+		atomic.AddUint64(&m.contended, 1)
+		m.hot.record(key, wait, false)
+	}
+}
+
+func (m *Metrics) timedOut(key string, wait time.Duration) {
+	if m == nil {
+		return
+	}
+
+	atomic.AddUint64(&m.timeouts, 1)
+	m.hot.record(key, wait, true)
+}
+
+// released records how long a key stayed locked, from its first holder to the
+// release of the last one.
+func (m *Metrics) released(held time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.holdTime.observe(held)
+}
+
+// MetricsSnapshot is a copy of the metrics at some point in time.
+type MetricsSnapshot struct {
+	Acquisitions uint64
+	// Contended counts the acquisitions that had to wait in line.
+	Contended uint64
+	// Timeouts counts the waits given up on a deadline or a cancellation.
+	Timeouts uint64
+	WaitTime HistogramSnapshot
+	// HoldTime observes how long the keys stayed locked.
+	HoldTime HistogramSnapshot
+}
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		Acquisitions: atomic.LoadUint64(&m.acquisitions),
+		Contended:    atomic.LoadUint64(&m.contended),
+		Timeouts:     atomic.LoadUint64(&m.timeouts),
+		WaitTime:     m.waitTime.snapshot(),
+		HoldTime:     m.holdTime.snapshot(),
+	}
+}
+
+// KeyStat is the contention of a key over the hot key window.
+type KeyStat struct {
+	Key       string
+	Contended uint64
+	Timeouts  uint64
+	Wait      time.Duration
+}
+
+// TopKeys returns the k keys that waited the longest over the hot key window,
+// hottest first.
+func (m *Metrics) TopKeys(k int) []KeyStat {
+	return m.hot.top(k)
+}
+
+// HistogramSnapshot holds the observations of a histogram. Counts[i] is the
+// number of observations up to Bounds[i], not counting those of the previous
+// buckets, and the last count is the one of the observations above all the
+// bounds.
+type HistogramSnapshot struct {
+	Bounds []time.Duration
+	Counts []uint64
+	Count  uint64
+	Sum    time.Duration
+}
+
+type histogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+func newHistogram(bounds []time.Duration) *histogram {
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.count++
+	h.sum += d
+	h.mu.Unlock()
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return HistogramSnapshot{
+		Bounds: append([]time.Duration(nil), h.bounds...),
+		Counts: append([]uint64(nil), h.counts...),
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
+
+// hotKeys counts the contention per key in a ring of slots covering the
+// sliding window.
+type hotKeys struct {
+	mu    sync.Mutex
+	step  time.Duration
+	slots []hotSlot
+}
+
+type hotSlot struct {
+	// epoch is the step the slot holds the stats of.
+	epoch int64
+	stats map[string]*KeyStat
+}
+
+func newHotKeys(window time.Duration, n int) *hotKeys {
+	step := window / time.Duration(n)
+	if step <= 0 {
+		step = time.Second
+	}
+
+	return &hotKeys{step: step, slots: make([]hotSlot, n)}
+}
+
+func (hk *hotKeys) record(key string, wait time.Duration, timeout bool) {
+	epoch := time.Now().UnixNano() / int64(hk.step)
+
+	hk.mu.Lock()
+	defer hk.mu.Unlock()
+
+	slot := &hk.slots[epoch%int64(len(hk.slots))]
+	if slot.epoch != epoch || slot.stats == nil {
+		slot.epoch = epoch
+		slot.stats = make(map[string]*KeyStat)
+	}
+
+	st := slot.stats[key]
+	if st == nil {
+		st = &KeyStat{Key: key}
+		slot.stats[key] = st
+	}
+
+	st.Wait += wait
+	if timeout {
+		st.Timeouts++
+	} else {
+		st.Contended++
+	}
+}
+
+func (hk *hotKeys) top(k int) []KeyStat {
+	oldest := time.Now().UnixNano()/int64(hk.step) - int64(len(hk.slots)) + 1
+	merged := make(map[string]*KeyStat)
+
+	hk.mu.Lock()
+	for _, slot := range hk.slots {
+		if slot.epoch < oldest {
+			continue
+		}
+
+		for key, st := range slot.stats {
+			m := merged[key]
+			if m == nil {
+				m = &KeyStat{Key: key}
+				merged[key] = m
+			}
+
+			m.Contended += st.Contended
+			m.Timeouts += st.Timeouts
+			m.Wait += st.Wait
+		}
+	}
+	hk.mu.Unlock()
+
+	stats := make([]KeyStat, 0, len(merged))
+	for _, st := range merged {
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Wait != stats[j].Wait {
+			return stats[i].Wait > stats[j].Wait
+		}
+
+		return stats[i].Key < stats[j].Key
+	})
+
+	if k >= 0 && len(stats) > k {
+		stats = stats[:k]
+	}
+
+	return stats
+}
+
+// WritePrometheus writes the metrics in the Prometheus text format, the hot
+// keys as gauges labelled with the key.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	s := m.Snapshot()
+
+	writeCounter(bw, "keylock_acquisitions_total", "Lock acquisitions.", s.Acquisitions)
+	writeCounter(bw, "keylock_contended_acquisitions_total", "Lock acquisitions that had to wait.", s.Contended)
+	writeCounter(bw, "keylock_timeouts_total", "Lock waits given up on a timeout or a cancellation.", s.Timeouts)
+	writeHistogram(bw, "keylock_wait_seconds", "Time spent waiting for a lock.", s.WaitTime)
+	writeHistogram(bw, "keylock_hold_seconds", "Time keys stayed locked.", s.HoldTime)
+
+	top := m.TopKeys(m.topK)
+
+	fmt.Fprintf(bw, "# HELP keylock_hot_key_wait_seconds Time spent waiting for the hottest keys over the sliding window.\n")
+	fmt.Fprintf(bw, "# TYPE keylock_hot_key_wait_seconds gauge\n")
+	for _, st := range top {
+		fmt.Fprintf(bw, "keylock_hot_key_wait_seconds{key=\"%s\"} %s\n", escapeLabel(st.Key), formatSeconds(st.Wait))
+	}
+
+	fmt.Fprintf(bw, "# HELP keylock_hot_key_contended Contended acquisitions of the hottest keys over the sliding window.\n")
+	fmt.Fprintf(bw, "# TYPE keylock_hot_key_contended gauge\n")
+	for _, st := range top {
+		fmt.Fprintf(bw, "keylock_hot_key_contended{key=\"%s\"} %d\n", escapeLabel(st.Key), st.Contended)
+	}
+
+	fmt.Fprintf(bw, "# HELP keylock_hot_key_timeouts Timeouts of the hottest keys over the sliding window.\n")
+	fmt.Fprintf(bw, "# TYPE keylock_hot_key_timeouts gauge\n")
+	for _, st := range top {
+		fmt.Fprintf(bw, "keylock_hot_key_timeouts{key=\"%s\"} %d\n", escapeLabel(st.Key), st.Timeouts)
+	}
+
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WritePrometheus(w)
+}
+
+func writeCounter(w io.Writer, name, help string, v uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+}
+
+func writeHistogram(w io.Writer, name, help string, s HistogramSnapshot) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	var cumulative uint64
+	for i, bound := range s.Bounds {
+		cumulative += s.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, formatSeconds(bound), cumulative)
+	}
+
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, s.Count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, formatSeconds(s.Sum))
+	fmt.Fprintf(w, "%s_count %d\n", name, s.Count)
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,113 @@
+package keylock
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics(WithBuckets(time.Millisecond, 10*time.Millisecond, time.Second))
+	kl := New(WithMetrics(m))
+
+	kl.Lock("hot")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		kl.Unlock("hot")
+	}()
+	kl.Lock("hot")
+
+	assert.False(t, kl.TryLockWithTimeout("hot", 5*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	assert.Error(t, kl.RLockCtx(ctx, "hot"))
+	kl.Unlock("hot")
+
+	kl.RLock("cold")
+	kl.RLock("cold")
+	kl.RUnlock("cold")
+	kl.RUnlock("cold")
+
+	s := m.Snapshot()
+	assert.Equal(t, uint64(4), s.Acquisitions)
+	assert.Equal(t, uint64(1), s.Contended)
+	assert.Equal(t, uint64(2), s.Timeouts)
+
+	assert.Equal(t, uint64(4), s.WaitTime.Count)
+	assert.Equal(t, []uint64{3, 0, 1, 0}, s.WaitTime.Counts)
+	assert.True(t, s.WaitTime.Sum >= 20*time.Millisecond)
+
+	// "hot" was locked twice, the first time for more than 10ms, and "cold"
+	// once by two readers.
+	assert.Equal(t, uint64(3), s.HoldTime.Count)
+	assert.True(t, s.HoldTime.Counts[2] >= 1)
+
+	top := m.TopKeys(10)
+	if assert.Len(t, top, 1) {
+		assert.Equal(t, "hot", top[0].Key)
+		assert.Equal(t, uint64(1), top[0].Contended)
+		assert.Equal(t, uint64(2), top[0].Timeouts)
+		assert.True(t, top[0].Wait >= 30*time.Millisecond)
+	}
+}
+
+func TestMetrics_HotKeyWindow(t *testing.T) {
+	m := NewMetrics(WithHotKeyWindow(40*time.Millisecond, 4))
+
+	m.acquired("old", time.Second, true)
+	time.Sleep(60 * time.Millisecond)
+	m.acquired("a", 2*time.Millisecond, true)
+	m.acquired("b", 3*time.Millisecond, true)
+	m.acquired("a", 2*time.Millisecond, true)
+	m.timedOut("c", time.Millisecond)
+
+	top := m.TopKeys(2)
+	assert.Equal(t, []KeyStat{
+		{Key: "a", Contended: 2, Wait: 4 * time.Millisecond},
+		{Key: "b", Contended: 1, Wait: 3 * time.Millisecond},
+	}, top)
+}
+
+func TestMetrics_WritePrometheus(t *testing.T) {
+	m := NewMetrics(WithBuckets(time.Millisecond, time.Second), WithTopK(1))
+
+	m.acquired("x", 0, false)
+	m.acquired(`tenant "a"`, 2*time.Millisecond, true)
+	m.timedOut("b", time.Millisecond)
+	m.released(1500 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.WritePrometheus(&buf))
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE keylock_acquisitions_total counter",
+		"keylock_acquisitions_total 2",
+		"keylock_contended_acquisitions_total 1",
+		"keylock_timeouts_total 1",
+		"# TYPE keylock_wait_seconds histogram",
+		`keylock_wait_seconds_bucket{le="0.001"} 1`,
+		`keylock_wait_seconds_bucket{le="1"} 2`,
+		`keylock_wait_seconds_bucket{le="+Inf"} 2`,
+		"keylock_wait_seconds_sum 0.002",
+		"keylock_wait_seconds_count 2",
+		`keylock_hold_seconds_bucket{le="1"} 0`,
+		`keylock_hold_seconds_bucket{le="+Inf"} 1`,
+		`keylock_hot_key_wait_seconds{key="tenant \"a\""} 0.002`,
+		`keylock_hot_key_contended{key="tenant \"a\""} 1`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.False(t, strings.Contains(out, `key="b"`))
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, out, rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+}
@@ -0,0 +1,269 @@
+package keylock
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+
+	cmap "github.com/orcaman/concurrent-map/v2"
+)
+
+var (
+	ErrWeightIsNotPositive = errors.New("keylock: semaphore weight is not positive")
+	ErrWeightExceedsLimit  = errors.New("keylock: semaphore weight exceeds the limit of the key")
+)
+
+type SemaphoreOption func(*semaphoreOptions)
+
+type semaphoreOptions struct {
+	defaultLimit int
+	limits       map[string]int
+}
+
+// WithDefaultLimit sets the limit of the keys without their own, 1 by
+// default.
+func WithDefaultLimit(n int) SemaphoreOption {
+	return func(o *semaphoreOptions) { o.defaultLimit = n }
+}
+
+func WithKeyLimit(key string, n int) SemaphoreOption {
+	return func(o *semaphoreOptions) {
+		if o.limits == nil {
+			o.limits = make(map[string]int)
+		}
+		o.limits[key] = n
+	}
+}
+
+type semWaiter struct {
+	n       int
+	granted bool
+	ready   chan struct{}
+}
+
+// semEntry is the state of a key in use, only accessed under the lock of its
+// shard like lockEntry.
+type semEntry struct {
+	limit   int
+	holders int
+	waiters int
+
+	// queue holds the *semWaiter in FIFO order.
+	queue list.List
+}
+
+// grant serves the waiters at the head of the queue while their weight fits.
+func (se *semEntry) grant() {
+	for e := se.queue.Front(); e != nil; e = se.queue.Front() {
+		w := e.Value.(*semWaiter)
+		if se.holders+w.n > se.limit {
+			return
+		}
+
+		se.queue.Remove(e)
+		se.waiters--
+		se.holders += w.n
+
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (se *semEntry) idle() bool {
+	return se.holders == 0 && se.waiters == 0
+}
+
+// KeySemaphore caps the number of concurrent holders of every key, such as
+// the running exports of every customer. Like KeyLock it only keeps the keys
+// in use and serves the waiters of a key in FIFO order.
+type KeySemaphore struct {
+	shards cmap.ConcurrentMap[string, *semEntry]
+
+	mu           sync.RWMutex
+	defaultLimit int
+	limits       map[string]int
+}
+
+func NewKeySemaphore(opts ...SemaphoreOption) *KeySemaphore {
+	o := semaphoreOptions{defaultLimit: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	limits := make(map[string]int, len(o.limits))
+	for key, n := range o.limits {
+		limits[key] = n
+	}
+
+	return &KeySemaphore{
+		shards:       cmap.New[*semEntry](),
+		defaultLimit: o.defaultLimit,
+		limits:       limits,
+	}
+}
+
+func (ks *KeySemaphore) limitOf(key string) int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if n, ok := ks.limits[key]; ok {
+		return n
+	}
+
+	return ks.defaultLimit
+}
+
+// SetLimit changes the limit of key, waking up the waiters that fit in a
+// raised limit. Holders above a lowered limit keep their slots, and waiters
+// asking for more than it wait until it is raised or their ctx is done.
+func (ks *KeySemaphore) SetLimit(key string, n int) {
+	ks.mu.Lock()
+	ks.limits[key] = n
+	ks.mu.Unlock()
+
+	ks.shards.RemoveCb(key, func(key string, se *semEntry, exists bool) bool {
+		if exists {
+			se.limit = n
+			se.grant()
+		}
+
+		return false
+	})
+}
+
+// prepare takes n slots of key when they are free, and queues a waiter
+// otherwise unless wait is false.
+func (ks *KeySemaphore) prepare(key string, n int, wait bool) (w *semWaiter, acquired bool, err error) {
+	ks.shards.Upsert(key, nil, func(exist bool, valueInMap, newValue *semEntry) *semEntry {
+		se := valueInMap
+		if !exist {
+			// Under the shard lock a SetLimit racing with the new entry either
+			// sets the limit first or finds the entry to update.
+			se = &semEntry{limit: ks.limitOf(key)}
+		}
+
+		switch {
+		case n > se.limit:
+			err = ErrWeightExceedsLimit
+		case se.queue.Len() == 0 && se.holders+n <= se.limit:
+			se.holders += n
+			acquired = true
+		case wait:
+			w = &semWaiter{n: n, ready: make(chan struct{})}
+			se.queue.PushBack(w)
+			se.waiters++
+		}
+
+		return se
+	})
+
+	if err != nil {
+		// The entry may have been created for nothing.
+		ks.shards.RemoveCb(key, func(key string, se *semEntry, exists bool) bool {
+			return exists && se.idle()
+		})
+	}
+
+	return w, acquired, err
+}
+
+// Acquire takes n slots of key, waiting in line until ctx is done.
+func (ks *KeySemaphore) Acquire(ctx context.Context, key string, n int) error {
+	if n <= 0 {
+		return ErrWeightIsNotPositive
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	w, acquired, err := ks.prepare(key, n, true)
+	if err != nil || acquired {
+		return err
+	}
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		if ks.cancel(key, w) {
+			return nil
+		}
+
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes n slots of key only if they are free right away.
+func (ks *KeySemaphore) TryAcquire(key string, n int) bool {
+	if n <= 0 {
+		return false
+	}
+
+	_, acquired, _ := ks.prepare(key, n, false)
+
+	return acquired
+}
+
+// cancel dequeues w. It reports true when w was granted its slots in the
+// meantime, which the caller then holds.
+func (ks *KeySemaphore) cancel(key string, w *semWaiter) bool {
+	cancelled := false
+
+	ks.shards.RemoveCb(key, func(key string, se *semEntry, exists bool) bool {
+		if !exists || w.granted {
+			return false
+		}
+
+		for e := se.queue.Front(); e != nil; e = e.Next() {
+			if e.Value.(*semWaiter) == w {
+				se.queue.Remove(e)
+				se.waiters--
+				break
+			}
+		}
+		cancelled = true
+
+		// A smaller waiter queued behind w may fit now.
+		se.grant()
+
+		return se.idle()
+	})
+
+	return !cancelled
+}
+
+// Release gives back n slots of key.
+func (ks *KeySemaphore) Release(key string, n int) {
+	misuse := false
+
+	ks.shards.RemoveCb(key, func(key string, se *semEntry, exists bool) bool {
+		if !exists || n <= 0 || n > se.holders {
+			misuse = true
+			return false
+		}
+
+		se.holders -= n
+		se.grant()
+
+		return se.idle()
+	})
+
+	// Panic outside of the callback, which runs with the shard locked.
+	if misuse {
+		panic("keylock: release of more slots than held")
+	}
+}
+
+// Status returns the slots of key in use and the number of waiters.
+func (ks *KeySemaphore) Status(key string) (holders int, waiters int) {
+	ks.shards.RemoveCb(key, func(key string, se *semEntry, exists bool) bool {
+		if exists {
+			holders, waiters = se.holders, se.waiters
+		}
+
+		return false
+	})
+
+	return holders, waiters
+}
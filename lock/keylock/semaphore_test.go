@@ -0,0 +1,112 @@
+package keylock
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySemaphore_Limit(t *testing.T) {
+	ks := NewKeySemaphore(WithDefaultLimit(2), WithKeyLimit("vip", 5))
+
+	var (
+		running, peak int32
+		wg            sync.WaitGroup
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			assert.NoError(t, ks.Acquire(context.Background(), "customer", 1))
+			defer ks.Release("customer", 1)
+
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&peak))
+
+	assert.True(t, ks.TryAcquire("vip", 5))
+	assert.False(t, ks.TryAcquire("vip", 1))
+	ks.Release("vip", 5)
+
+	for _, key := range []string{"customer", "vip"} {
+		holders, waiters := ks.Status(key)
+		assert.Equal(t, 0, holders, key)
+		assert.Equal(t, 0, waiters, key)
+	}
+}
+
+func TestKeySemaphore_Errors(t *testing.T) {
+	ks := NewKeySemaphore(WithDefaultLimit(3))
+
+	assert.ErrorIs(t, ks.Acquire(context.Background(), "key", 0), ErrWeightIsNotPositive)
+	assert.ErrorIs(t, ks.Acquire(context.Background(), "key", 4), ErrWeightExceedsLimit)
+	assert.False(t, ks.TryAcquire("key", 4))
+	assert.Panics(t, func() { ks.Release("key", 1) })
+
+	assert.NoError(t, ks.Acquire(context.Background(), "key", 3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ks.Acquire(ctx, "key", 1), context.DeadlineExceeded)
+
+	assert.Panics(t, func() { ks.Release("key", 4) })
+	ks.Release("key", 3)
+
+	holders, waiters := ks.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeySemaphore_FIFOAndSetLimit(t *testing.T) {
+	ks := NewKeySemaphore(WithDefaultLimit(2))
+
+	assert.True(t, ks.TryAcquire("key", 2))
+
+	big := make(chan error, 1)
+	go func() { big <- ks.Acquire(context.Background(), "key", 2) }()
+
+	assert.Eventually(t, func() bool {
+		_, waiters := ks.Status("key")
+		return waiters == 1
+	}, time.Second, time.Millisecond)
+
+	// A small request may not overtake the queued big one.
+	assert.False(t, ks.TryAcquire("key", 1))
+
+	ks.Release("key", 1)
+	select {
+	case <-big:
+		t.Fatal("the big request went through with a single free slot")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	ks.SetLimit("key", 3)
+	assert.NoError(t, <-big)
+
+	holders, waiters := ks.Status("key")
+	assert.Equal(t, 3, holders)
+	assert.Equal(t, 0, waiters)
+
+	ks.Release("key", 1)
+	ks.Release("key", 2)
+
+	holders, waiters = ks.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
@@ -0,0 +1,151 @@
+package keylock
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotReadLocked   = errors.New("keylock: key is not read-locked")
+	ErrNotWriteLocked  = errors.New("keylock: key is not write-locked")
+	ErrUpgradeConflict = errors.New("keylock: another reader is upgrading the key")
+	ErrGuardNotHeld    = errors.New("keylock: the guard no longer holds its lock")
+)
+
+// Upgrade turns the read lock of key the caller took with RLock into a write
+// lock without releasing it. It waits for the other readers to leave while
+// keeping new readers and writers out. Two readers upgrading at once would
+// wait for each other, so Upgrade fails with ErrUpgradeConflict while another
+// upgrade is pending, and the caller should RUnlock and retry. When ctx is
+// done first, the caller keeps its read lock.
+//
+// Upgrade cannot tell whether the caller holds a read lock at all: called
+// without one, it write-locks the key under the last reader. Guard.Upgrade
+// checks that the guard holds the lock.
+func (kl *KeyLock) Upgrade(ctx context.Context, key string) error {
+	return kl.upgrade(ctx, key, nil)
+}
+
+// Upgrade is KeyLock.Upgrade for the read lock of the guard, failing with
+// ErrGuardNotHeld once the lock was taken away from the guard.
+func (g *Guard) Upgrade(ctx context.Context) error {
+	if err := g.kl.upgrade(ctx, g.key, g.owner); err != nil {
+		return err
+	}
+	g.write = true
+
+	return nil
+}
+
+// upgrade upgrades the read lock of o, or of the caller when o is nil.
+func (kl *KeyLock) upgrade(ctx context.Context, key string, o *owner) error {
+	var (
+		w   *waiter
+		err error
+	)
+
+	kl.shards.RemoveCb(key, func(key string, le *lockEntry, exists bool) bool {
+		switch {
+		case o != nil && (!exists || le.owners[o.token] == nil):
+			err = ErrGuardNotHeld
+		case !exists || le.holders == 0 || le.writer:
+			err = ErrNotReadLocked
+		case le.upgrader != nil:
+			err = ErrUpgradeConflict
+		default:
+			w = &waiter{write: true, ready: make(chan struct{})}
+			le.upgrader = w
+			le.grant()
+		}
+
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		if kl.cancelUpgrade(key, w) {
+			return nil
+		}
+
+		return ctx.Err()
+	}
+}
+
+// cancelUpgrade gives the upgrade of w up. It reports true when w was
+// upgraded in the meantime, which the caller then holds.
+func (kl *KeyLock) cancelUpgrade(key string, w *waiter) bool {
+	cancelled := false
+
+	kl.shards.RemoveCb(key, func(key string, le *lockEntry, exists bool) bool {
+		if !exists || w.granted {
+			return false
+		}
+
+		le.upgrader = nil
+		cancelled = true
+
+		// The readers held back by the upgrade can go now.
+		le.grant()
+
+		return false
+	})
+
+	return !cancelled
+}
+
+// Downgrade turns the write lock of key the caller took with Lock or Upgrade
+// into a read lock without releasing it, letting in the readers queued
+// behind. Like Upgrade it does not check the caller holds the lock, which
+// Guard.Downgrade does. A Lease downgraded this way is released as a read
+// lock.
+func (kl *KeyLock) Downgrade(key string) {
+	misuse := false
+
+	kl.shards.RemoveCb(key, func(key string, le *lockEntry, exists bool) bool {
+		if !exists || !le.writer {
+			misuse = true
+			return false
+		}
+
+		le.writer = false
+		le.grant()
+
+		return false
+	})
+
+	// Panic outside of the callback, which runs with the shard locked.
+	if misuse {
+		panic("keylock: Downgrade of key not write-locked")
+	}
+}
+
+// Downgrade is KeyLock.Downgrade for the write lock of the guard, failing with
+// ErrGuardNotHeld once the lock was taken away from the guard.
+func (g *Guard) Downgrade() error {
+	var err error
+
+	g.kl.shards.RemoveCb(g.key, func(key string, le *lockEntry, exists bool) bool {
+		switch {
+		case !exists || le.owners[g.owner.token] == nil:
+			err = ErrGuardNotHeld
+		case !le.writer:
+			err = ErrNotWriteLocked
+		default:
+			le.writer = false
+			le.grant()
+		}
+
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	g.write = false
+
+	return nil
+}
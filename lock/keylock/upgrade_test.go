@@ -0,0 +1,175 @@
+package keylock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyLock_Upgrade(t *testing.T) {
+	kl := New()
+
+	assert.ErrorIs(t, kl.Upgrade(context.Background(), "key"), ErrNotReadLocked)
+
+	kl.RLock("key")
+	kl.RLock("key")
+
+	upgraded := make(chan error, 1)
+	go func() { upgraded <- kl.Upgrade(context.Background(), "key") }()
+
+	assert.Eventually(t, func() bool {
+		if kl.TryRLock("key") {
+			kl.RUnlock("key")
+			return false
+		}
+		return true
+	}, time.Second, time.Millisecond)
+
+	// The other reader would deadlock by upgrading too.
+	assert.ErrorIs(t, kl.Upgrade(context.Background(), "key"), ErrUpgradeConflict)
+
+	// A writer queued meanwhile goes after the upgrader.
+	locked := make(chan struct{})
+	go func() {
+		kl.Lock("key")
+		close(locked)
+	}()
+	assert.Eventually(t, func() bool {
+		_, waiters := kl.Status("key")
+		return waiters == 1
+	}, time.Second, time.Millisecond)
+
+	kl.RUnlock("key")
+	assert.NoError(t, <-upgraded)
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 1, holders)
+	assert.Equal(t, 1, waiters)
+	assert.False(t, kl.TryRLock("key"))
+
+	kl.Unlock("key")
+	<-locked
+	kl.Unlock("key")
+
+	holders, waiters = kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_UpgradeCancelled(t *testing.T) {
+	kl := New()
+
+	kl.RLock("key")
+	kl.RLock("key")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, kl.Upgrade(ctx, "key"), context.DeadlineExceeded)
+
+	// The upgrader still holds its read lock and readers come in again.
+	assert.True(t, kl.TryRLock("key"))
+	holders, _ := kl.Status("key")
+	assert.Equal(t, 3, holders)
+
+	kl.RUnlock("key")
+	kl.RUnlock("key")
+	assert.NoError(t, kl.Upgrade(context.Background(), "key"))
+	kl.Unlock("key")
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_Downgrade(t *testing.T) {
+	kl := New()
+
+	assert.Panics(t, func() { kl.Downgrade("key") })
+
+	kl.Lock("key")
+
+	rlocked := make(chan struct{})
+	go func() {
+		kl.RLock("key")
+		close(rlocked)
+	}()
+	assert.Eventually(t, func() bool {
+		_, waiters := kl.Status("key")
+		return waiters == 1
+	}, time.Second, time.Millisecond)
+
+	kl.Downgrade("key")
+	<-rlocked
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 2, holders)
+	assert.Equal(t, 0, waiters)
+	assert.False(t, kl.TryLock("key"))
+
+	kl.RUnlock("key")
+	kl.RUnlock("key")
+
+	holders, waiters = kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestKeyLock_UpgradeAndDowngradeGuards(t *testing.T) {
+	var misuses []*Misuse
+	kl := New(WithStrictMode(func(m *Misuse) { misuses = append(misuses, m) }))
+
+	g, err := kl.Acquire("key")
+	assert.NoError(t, err)
+	assert.NoError(t, g.Downgrade())
+	assert.ErrorIs(t, g.Downgrade(), ErrNotWriteLocked)
+	assert.True(t, kl.TryRLock("key"))
+	kl.RUnlock("key")
+	g.Release()
+	assert.False(t, g.Held())
+
+	g, err = kl.RAcquire("key")
+	assert.NoError(t, err)
+	assert.NoError(t, g.Upgrade(context.Background()))
+	assert.ErrorIs(t, g.Upgrade(context.Background()), ErrNotReadLocked)
+	assert.False(t, kl.TryRLock("key"))
+	g.Release()
+
+	// The key-based forms work on the lock of a lease too.
+	l, err := kl.LockWithLease("key", time.Hour)
+	assert.NoError(t, err)
+	kl.Downgrade("key")
+	assert.NoError(t, l.Release())
+
+	assert.Empty(t, misuses)
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
+
+func TestGuard_UpgradeChecksOwner(t *testing.T) {
+	kl := New()
+
+	g, err := kl.RAcquire("key")
+	assert.NoError(t, err)
+	g.Release()
+
+	// Another reader holds the key, not the released guard.
+	kl.RLock("key")
+	assert.ErrorIs(t, g.Upgrade(context.Background()), ErrGuardNotHeld)
+	assert.True(t, kl.TryRLock("key"))
+	kl.RUnlock("key")
+	kl.RUnlock("key")
+
+	kl.Lock("key")
+	assert.ErrorIs(t, g.Downgrade(), ErrGuardNotHeld)
+	assert.False(t, kl.TryRLock("key"))
+	kl.Unlock("key")
+
+	holders, waiters := kl.Status("key")
+	assert.Equal(t, 0, holders)
+	assert.Equal(t, 0, waiters)
+}
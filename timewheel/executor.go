@@ -0,0 +1,132 @@
+package timewheel
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pqiaohaoq/gotools/log"
+)
+
+// OverrunHook is called as soon as a task exceeds its execution limit, while
+// it may still be running, with the time elapsed since it was due.
+type OverrunHook func(key string, runtime time.Duration)
+
+func WithMaxExecution(d time.Duration) Option { return func(o *Options) { o.maxExecution = d } }
+func WithOverrunHook(h OverrunHook) Option    { return func(o *Options) { o.overrunHook = h } }
+
+// WithPrefixLimit caps the number of concurrently running tasks whose key
+// starts with prefix. When several prefixes match, the longest one applies.
+//
+// The wait for a slot counts towards the execution limit of a task: a task
+// whose limit passes while it waits is dropped without running and reported
+// as an overrun. A running task keeps its slot until it returns, even past
+// its limit, so tasks ignoring their context never run more than n at once.
+// Tasks without an execution limit wait for a slot as long as it takes, each
+// on its own goroutine.
+func WithPrefixLimit(prefix string, n int) Option {
+	return func(o *Options) {
+		if o.prefixLimits == nil {
+			o.prefixLimits = make(map[string]int)
+		}
+		o.prefixLimits[prefix] = n
+	}
+}
+
+type TaskOption func(*Task)
+
+// WithTaskTimeout overrides the scheduler wide execution limit for one task.
+func WithTaskTimeout(d time.Duration) TaskOption { return func(t *Task) { t.timeout = d } }
+
+type prefixLimit struct {
+	prefix string
+	slots  chan struct{}
+}
+
+type executor struct {
+	maxExecution time.Duration
+	overrunHook  OverrunHook
+	limits       []prefixLimit
+
+	logger log.Logger
+}
+
+func newExecutor(o Options) *executor {
+	e := &executor{
+		maxExecution: o.maxExecution,
+		overrunHook:  o.overrunHook,
+		logger:       o.logger,
+	}
+
+	for prefix, n := range o.prefixLimits {
+		if n <= 0 {
+			continue
+		}
+		e.limits = append(e.limits, prefixLimit{prefix: prefix, slots: make(chan struct{}, n)})
+	}
+
+	sort.Slice(e.limits, func(i, j int) bool { return len(e.limits[i].prefix) > len(e.limits[j].prefix) })
+
+	return e
+}
+
+func (e *executor) limitOf(key string) chan struct{} {
+	for _, l := range e.limits {
+		if strings.HasPrefix(key, l.prefix) {
+			return l.slots
+		}
+	}
+
+	return nil
+}
+
+func (e *executor) execute(key string, task *Task) {
+	timeout := task.timeout
+	if timeout <= 0 {
+		timeout = e.maxExecution
+	}
+
+	start := time.Now()
+
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	if slots := e.limitOf(key); slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			// The task never runs, its limit passed before a slot was free.
+			e.logger.Warnf("the task %s waited for a slot beyond its execution limit %s, drop it", key, timeout)
+			e.reportOverrun(key, time.Since(start))
+
+			return
+		}
+
+		// The slot stays taken until runFunc returns, even past the limit.
+		defer func() { <-slots }()
+	}
+
+	if timeout > 0 {
+		deadline := time.AfterFunc(time.Until(start.Add(timeout)), func() {
+			runtime := time.Since(start)
+			e.logger.Warnf("the task %s overran its execution limit %s, runtime: %s", key, timeout, runtime)
+
+			e.reportOverrun(key, runtime)
+		})
+		defer deadline.Stop()
+	}
+
+	e.logger.Debugf("execute the task %s", key)
+
+	task.runFunc(ctx)
+}
+
+func (e *executor) reportOverrun(key string, runtime time.Duration) {
+	if e.overrunHook != nil {
+		e.overrunHook(key, runtime)
+	}
+}
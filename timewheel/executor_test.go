@@ -0,0 +1,152 @@
+package timewheel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExecutorOverrun(t *testing.T) {
+	type overrun struct {
+		key     string
+		runtime time.Duration
+	}
+	overruns := make(chan overrun, 1)
+
+	e := newExecutor(Options{
+		logger:       defaultOptions.logger,
+		maxExecution: time.Second,
+		overrunHook: func(key string, d time.Duration) {
+			overruns <- overrun{key: key, runtime: d}
+		},
+	})
+
+	cancelled := make(chan bool, 1)
+	task := &Task{
+		timeout: 10 * time.Millisecond,
+		runFunc: func(ctx context.Context) {
+			<-ctx.Done()
+			cancelled <- ctx.Err() == context.DeadlineExceeded
+			time.Sleep(5 * time.Millisecond)
+		},
+	}
+	e.execute("slow", task)
+
+	assert.True(t, <-cancelled)
+	o := <-overruns
+	assert.Equal(t, "slow", o.key)
+	assert.True(t, o.runtime >= 10*time.Millisecond)
+}
+
+func Test_ExecutorHungTask(t *testing.T) {
+	overruns := make(chan string, 2)
+
+	e := newExecutor(Options{
+		logger:       defaultOptions.logger,
+		maxExecution: 10 * time.Millisecond,
+		prefixLimits: map[string]int{"hung:": 1},
+		overrunHook:  func(key string, _ time.Duration) { overruns <- key },
+	})
+
+	hang := make(chan struct{})
+	returned := make(chan struct{})
+	go func() {
+		e.execute("hung:a", &Task{runFunc: func(context.Context) { <-hang }})
+		close(returned)
+	}()
+
+	// The overrun is reported while the task still hangs.
+	select {
+	case key := <-overruns:
+		assert.Equal(t, "hung:a", key)
+	case <-time.After(time.Second):
+		t.Fatal("the hung task was not reported")
+	}
+
+	// but it keeps its slot, so the next task is dropped.
+	ran := false
+	e.execute("hung:b", &Task{runFunc: func(context.Context) { ran = true }})
+	assert.False(t, ran)
+	assert.Equal(t, "hung:b", <-overruns)
+
+	close(hang)
+	<-returned
+
+	e.execute("hung:c", &Task{runFunc: func(context.Context) { ran = true }})
+	assert.True(t, ran)
+}
+
+func Test_ExecutorSlotWaitCountsTowardsLimit(t *testing.T) {
+	overruns := make(chan string, 1)
+
+	e := newExecutor(Options{
+		logger:       defaultOptions.logger,
+		prefixLimits: map[string]int{"busy:": 1},
+		overrunHook:  func(key string, _ time.Duration) { overruns <- key },
+	})
+
+	release := make(chan struct{})
+	go e.execute("busy:a", &Task{runFunc: func(context.Context) { <-release }})
+	time.Sleep(5 * time.Millisecond)
+
+	ran := false
+	e.execute("busy:b", &Task{timeout: 10 * time.Millisecond, runFunc: func(context.Context) { ran = true }})
+	close(release)
+
+	assert.False(t, ran)
+	assert.Equal(t, "busy:b", <-overruns)
+}
+
+func Test_ExecutorPrefixLimit(t *testing.T) {
+	e := newExecutor(Options{
+		logger:       defaultOptions.logger,
+		prefixLimits: map[string]int{"report:": 2, "report:daily:": 1},
+	})
+
+	var running, peak int32
+	run := &Task{runFunc: func(context.Context) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.execute("report:daily:tenant", run)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak))
+}
+
+func Test_HeapSchedulerTaskTimeout(t *testing.T) {
+	overrun := make(chan string, 1)
+
+	hs := NewHeapScheduler(WithOverrunHook(func(key string, _ time.Duration) { overrun <- key }))
+	hs.Start()
+	defer hs.Stop()
+
+	err := hs.AddTaskCtx(time.Millisecond, "key", func(ctx context.Context) { <-ctx.Done() }, WithTaskTimeout(10*time.Millisecond))
+	assert.NoError(t, err)
+
+	select {
+	case key := <-overrun:
+		assert.Equal(t, "key", key)
+	case <-time.After(time.Second):
+		t.Fatal("overrun was not reported")
+	}
+}
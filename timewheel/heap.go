@@ -0,0 +1,202 @@
+package timewheel
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pqiaohaoq/gotools/log"
+)
+
+type HeapScheduler struct {
+	mu    sync.Mutex
+	tasks taskHeap
+	keys  map[string]*heapTask
+
+	timer *time.Timer
+
+	wakeChannel chan struct{}
+	stopChannel chan struct{}
+
+	executor *executor
+	logger   log.Logger
+}
+
+type heapTask struct {
+	key   string
+	runAt time.Time
+	index int
+
+	task *Task
+}
+
+type taskHeap []*heapTask
+
+func (h taskHeap) Len() int           { return len(h) }
+func (h taskHeap) Less(i, j int) bool { return h[i].runAt.Before(h[j].runAt) }
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x any) {
+	t := x.(*heapTask)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+
+	return t
+}
+
+func NewHeapScheduler(options ...Option) *HeapScheduler {
+	o := applyOpts(options)
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	return &HeapScheduler{
+		keys: make(map[string]*heapTask),
+
+		timer: timer,
+
+		wakeChannel: make(chan struct{}, 1),
+		stopChannel: make(chan struct{}),
+
+		executor: newExecutor(o),
+		logger:   o.logger,
+	}
+}
+
+func (hs *HeapScheduler) Start() {
+	go hs.start()
+
+	hs.logger.Infof("start the heap scheduler")
+}
+
+func (hs *HeapScheduler) start() {
+	hs.resetTimer()
+
+	for {
+		select {
+		case <-hs.timer.C:
+			hs.runExpiredTasks()
+			hs.resetTimer()
+		case <-hs.wakeChannel:
+			hs.resetTimer()
+		case <-hs.stopChannel:
+			hs.timer.Stop()
+			return
+		}
+	}
+}
+
+func (hs *HeapScheduler) Stop() {
+	hs.stopChannel <- struct{}{}
+
+	hs.logger.Infof("stop the heap scheduler")
+}
+
+func (hs *HeapScheduler) AddTask(delay time.Duration, key string, taskFunc TaskFunc) error {
+	return hs.AddTaskCtx(delay, key, func(context.Context) { taskFunc() })
+}
+
+func (hs *HeapScheduler) AddTaskCtx(delay time.Duration, key string, taskFunc CtxTaskFunc, opts ...TaskOption) error {
+	if delay <= 0 {
+		return ErrTaskDelayIsNotPositive
+	}
+	if key == "" {
+		return ErrTaskKeyIsEmpty
+	}
+
+	hs.mu.Lock()
+	if _, ok := hs.keys[key]; ok {
+		hs.mu.Unlock()
+		return ErrTaskDuplicatedKey
+	}
+
+	now := time.Now()
+	ht := &heapTask{
+		key:   key,
+		runAt: now.Add(delay),
+		task: &Task{
+			delay:   delay,
+			addTime: now,
+			runFunc: taskFunc,
+		},
+	}
+	for _, opt := range opts {
+		opt(ht.task)
+	}
+
+	hs.keys[key] = ht
+	heap.Push(&hs.tasks, ht)
+	earliest := ht.index == 0
+	hs.mu.Unlock()
+
+	if earliest {
+		hs.wake()
+	}
+
+	hs.logger.Debugf("add the task %s with delay %s into the heap (run at: %s)", key, delay, ht.runAt)
+
+	return nil
+}
+
+func (hs *HeapScheduler) RemoveTask(key string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	ht, ok := hs.keys[key]
+	if !ok {
+		return
+	}
+
+	delete(hs.keys, key)
+	heap.Remove(&hs.tasks, ht.index)
+}
+
+func (hs *HeapScheduler) wake() {
+	select {
+	case hs.wakeChannel <- struct{}{}:
+	default:
+	}
+}
+
+// resetTimer arms the timer for the earliest task. A stale fire left over
+// from a previous deadline only results in an empty scan.
+func (hs *HeapScheduler) resetTimer() {
+	hs.timer.Stop()
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if len(hs.tasks) == 0 {
+		return
+	}
+
+	hs.timer.Reset(time.Until(hs.tasks[0].runAt))
+}
+
+func (hs *HeapScheduler) runExpiredTasks() {
+	now := time.Now()
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	for len(hs.tasks) > 0 && !hs.tasks[0].runAt.After(now) {
+		ht := heap.Pop(&hs.tasks).(*heapTask)
+		delete(hs.keys, ht.key)
+
+		go hs.executor.execute(ht.key, ht.task)
+	}
+}
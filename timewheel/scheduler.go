@@ -0,0 +1,42 @@
+package timewheel
+
+import "time"
+
+// Scheduler runs keyed tasks once after a delay. TimeWheel trades precision
+// for cheap bookkeeping of many tasks, HeapScheduler fires every task at its
+// exact deadline.
+type Scheduler interface {
+	Start()
+	Stop()
+
+	AddTask(delay time.Duration, key string, taskFunc TaskFunc) error
+	AddTaskCtx(delay time.Duration, key string, taskFunc CtxTaskFunc, opts ...TaskOption) error
+	RemoveTask(key string)
+}
+
+var (
+	_ Scheduler = (*TimeWheel)(nil)
+	_ Scheduler = (*HeapScheduler)(nil)
+)
+
+type Backend string
+
+const (
+	BackendTimeWheel Backend = "timewheel"
+	BackendHeap      Backend = "heap"
+)
+
+func WithBackend(b Backend) Option { return func(o *Options) { o.backend = b } }
+
+// NewScheduler builds the scheduler selected by WithBackend, falling back to
+// the TimeWheel for an empty or unknown backend.
+func NewScheduler(options ...Option) Scheduler {
+	o := applyOpts(options)
+
+	switch o.backend {
+	case BackendHeap:
+		return NewHeapScheduler(options...)
+	default:
+		return NewTimeWheel(options...)
+	}
+}
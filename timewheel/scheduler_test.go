@@ -0,0 +1,105 @@
+package timewheel
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewScheduler(t *testing.T) {
+	_, ok := NewScheduler().(*TimeWheel)
+	assert.True(t, ok)
+
+	_, ok = NewScheduler(WithBackend(BackendHeap)).(*HeapScheduler)
+	assert.True(t, ok)
+}
+
+func Test_HeapSchedulerRunTask(t *testing.T) {
+	hs := NewHeapScheduler()
+	hs.Start()
+	defer hs.Stop()
+
+	var count int32
+	done := make(chan struct{})
+
+	assert.NoError(t, hs.AddTask(20*time.Millisecond, "late", func() {
+		atomic.AddInt32(&count, 1)
+		close(done)
+	}))
+	assert.NoError(t, hs.AddTask(time.Millisecond, "early", func() { atomic.AddInt32(&count, 1) }))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("task was not executed")
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func Test_HeapSchedulerRemoveTask(t *testing.T) {
+	hs := NewHeapScheduler()
+	hs.Start()
+	defer hs.Stop()
+
+	var count int32
+	assert.NoError(t, hs.AddTask(20*time.Millisecond, "key", func() { atomic.AddInt32(&count, 1) }))
+	hs.RemoveTask("key")
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&count))
+}
+
+func Test_HeapSchedulerAddTaskErrors(t *testing.T) {
+	hs := NewHeapScheduler()
+
+	assert.Equal(t, ErrTaskDelayIsNotPositive, hs.AddTask(0, "key", func() {}))
+	assert.Equal(t, ErrTaskKeyIsEmpty, hs.AddTask(time.Second, "", func() {}))
+	assert.NoError(t, hs.AddTask(time.Second, "key", func() {}))
+	assert.Equal(t, ErrTaskDuplicatedKey, hs.AddTask(time.Second, "key", func() {}))
+}
+
+func benchmarkAddRemove(b *testing.B, s Scheduler) {
+	s.Start()
+	defer s.Stop()
+
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := s.AddTask(time.Minute, key, func() {}); err != nil {
+			b.Fatal(err)
+		}
+		s.RemoveTask(key)
+	}
+}
+
+func BenchmarkScheduler_AddRemove(b *testing.B) {
+	b.Run("timewheel", func(b *testing.B) { benchmarkAddRemove(b, NewTimeWheel()) })
+	b.Run("heap", func(b *testing.B) { benchmarkAddRemove(b, NewHeapScheduler()) })
+}
+
+func benchmarkExpire(b *testing.B, s Scheduler, delay time.Duration) {
+	s.Start()
+	defer s.Stop()
+
+	done := make(chan struct{}, b.N)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.AddTask(delay, fmt.Sprintf("key-%d", i), func() { done <- struct{}{} }); err != nil {
+			b.Fatal(err)
+		}
+	}
+	for i := 0; i < b.N; i++ {
+		<-done
+	}
+}
+
+func BenchmarkScheduler_Expire(b *testing.B) {
+	b.Run("timewheel", func(b *testing.B) {
+		benchmarkExpire(b, NewTimeWheel(WithTickerInterval(10*time.Millisecond)), 10*time.Millisecond)
+	})
+	b.Run("heap", func(b *testing.B) { benchmarkExpire(b, NewHeapScheduler(), 10*time.Millisecond) })
+}
@@ -1,6 +1,7 @@
 package timewheel
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"time"
@@ -15,6 +16,7 @@ var (
 	ErrTaskDelayLessThanTickInterval = errors.New("task delay duration is less than 10ms")
 	ErrDelayLessThanTickInterval     = errors.New("task delay duration is less than tick interval")
 	ErrTaskDuplicatedKey             = errors.New("duplicated task key")
+	ErrTaskDelayIsNotPositive        = errors.New("task delay duration is not positive")
 )
 
 var (
@@ -37,17 +39,23 @@ type TimeWheel struct {
 
 	stopChannel chan struct{}
 
-	logger log.Logger
+	executor *executor
+	logger   log.Logger
 }
 
 type TaskFunc func()
 
+// CtxTaskFunc receives a context that is cancelled once the task exceeds its
+// execution limit.
+type CtxTaskFunc func(ctx context.Context)
+
 type Task struct {
 	delay   time.Duration
 	circle  int
 	addTime time.Time
+	timeout time.Duration
 
-	runFunc TaskFunc
+	runFunc CtxTaskFunc
 }
 
 type Option func(*Options)
@@ -56,6 +64,11 @@ type Options struct {
 	slotNum        int
 	tickerInterval time.Duration
 	logger         log.Logger
+	backend        Backend
+
+	maxExecution time.Duration
+	overrunHook  OverrunHook
+	prefixLimits map[string]int
 }
 
 func WithTickerInterval(d time.Duration) Option { return func(o *Options) { o.tickerInterval = d } }
@@ -73,22 +86,22 @@ func applyOpts(opts []Option) Options {
 }
 
 var (
-	_globalTW = NewTimeWheel()
+	_globalS  Scheduler = NewTimeWheel()
 	_globalMu sync.RWMutex
 )
 
-func ReplaceGlobals(tw *TimeWheel) {
+func ReplaceGlobals(s Scheduler) {
 	_globalMu.Lock()
-	_globalTW = tw
+	_globalS = s
 	_globalMu.Unlock()
 }
 
-func L() *TimeWheel {
+func L() Scheduler {
 	_globalMu.RLock()
-	tw := _globalTW
+	s := _globalS
 	_globalMu.RUnlock()
 
-	return tw
+	return s
 }
 
 func NewTimeWheel(options ...Option) *TimeWheel {
@@ -104,7 +117,8 @@ func NewTimeWheel(options ...Option) *TimeWheel {
 
 		stopChannel: make(chan struct{}),
 
-		logger: o.logger,
+		executor: newExecutor(o),
+		logger:   o.logger,
 	}
 
 	tw.slots = make([]*safe.Map[string, *Task], tw.slotNum)
@@ -151,6 +165,10 @@ func (tw *TimeWheel) Stop() {
 }
 
 func (tw *TimeWheel) AddTask(delay time.Duration, key string, taskFunc TaskFunc) error {
+	return tw.AddTaskCtx(delay, key, func(context.Context) { taskFunc() })
+}
+
+func (tw *TimeWheel) AddTaskCtx(delay time.Duration, key string, taskFunc CtxTaskFunc, opts ...TaskOption) error {
 	if delay < 10*time.Millisecond {
 		return ErrTaskDelayLessThanTickInterval
 	}
@@ -173,6 +191,9 @@ func (tw *TimeWheel) AddTask(delay time.Duration, key string, taskFunc TaskFunc)
 		addTime: time.Now(),
 		runFunc: taskFunc,
 	}
+	for _, opt := range opts {
+		opt(task)
+	}
 
 	tw.keyPosition.Set(key, position)
 	slot := tw.slots[position]
@@ -223,8 +244,7 @@ func (tw *TimeWheel) scanAndRunTask(position int) {
 			slot.Remove(key)
 			tw.keyPosition.Remove(key)
 
-			tw.logger.Debugf("execute the task %s", key)
-			task.runFunc()
+			tw.executor.execute(key, task)
 		}()
 	}
 }